@@ -0,0 +1,20 @@
+module github.com/VideoCoin/cli
+
+go 1.12
+
+require (
+	github.com/briandowns/spinner v1.11.1
+	github.com/btcsuite/btcd v0.20.1-beta
+	github.com/btcsuite/btcutil v1.0.3-0.20201208143702-a53e38424cce
+	github.com/kelseyhightower/envconfig v1.4.0
+	github.com/mgutz/ansi v0.0.0-20200706080929-d51e80ef957d // indirect
+	github.com/nareix/joy4 v0.0.0-20200507095837-05a4ffbb5369
+	github.com/pborman/uuid v0.0.0-20170612153648-e790cca94e6c
+	github.com/prometheus/client_golang v0.9.2
+	github.com/sirupsen/logrus v1.4.2
+	github.com/spf13/cobra v0.0.5
+	github.com/tyler-smith/go-bip39 v1.0.2
+	github.com/x-cray/logrus-prefixed-formatter v0.5.2
+	golang.org/x/crypto v0.0.0-20200115085410-6d4e4cb37c7d
+	gopkg.in/yaml.v2 v2.2.2
+)
@@ -2,6 +2,7 @@ package cloud
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,10 +11,20 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/VideoCoin/cli/internal/metrics"
 	"github.com/VideoCoin/common/proto"
 	"github.com/sirupsen/logrus"
 )
 
+// DefaultProfileID is the transcoding profile of created jobs.
+const DefaultProfileID = 1
+
+const (
+	watchInterval    = 5 * time.Second
+	watchMaxDelay    = time.Minute
+	watchMaxFailures = 5
+)
+
 type CloudManagerConfig struct {
 	ManagerAddr string
 	Logger      *logrus.Entry
@@ -31,6 +42,22 @@ type Job struct {
 	Profile   string `json:"profile"`
 }
 
+var terminatedJobStatuses = []proto.WorkOrderStatus{
+	proto.WorkOrderStatusCanceled,
+	proto.WorkOrderStatusFailed,
+	proto.WorkOrderStatusCompleted,
+}
+
+func (j *Job) IsTerminated() bool {
+	for _, status := range terminatedJobStatuses {
+		if j.Status == status.String() {
+			return true
+		}
+	}
+
+	return false
+}
+
 func NewCloudManager(c CloudManagerConfig) *cloudManager {
 	return &cloudManager{
 		httpClient:  http.Client{Timeout: time.Second * 5},
@@ -39,13 +66,28 @@ func NewCloudManager(c CloudManagerConfig) *cloudManager {
 	}
 }
 
+// do sends the request and records its latency under endpoint.
+func (c *cloudManager) do(endpoint string, req *http.Request) (*http.Response, error) {
+	started := time.Now()
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		metrics.ObserveCloudRequest(endpoint, 0, started)
+		return nil, err
+	}
+
+	metrics.ObserveCloudRequest(endpoint, res.StatusCode, started)
+
+	return res, nil
+}
+
 func (c *cloudManager) CreateJob(streamID *big.Int, address string) (string, error) {
 	addr := fmt.Sprintf("%s/api/v1/job", c.managerAddr)
 
 	jobRequest := &proto.AddJobRequest{
 		StreamId:      streamID.Int64(),
 		WalletAddress: address,
-		ProfileId:     1,
+		ProfileId:     DefaultProfileID,
 	}
 
 	buff := new(bytes.Buffer)
@@ -59,7 +101,7 @@ func (c *cloudManager) CreateJob(streamID *big.Int, address string) (string, err
 		return "", err
 	}
 
-	res, err := c.httpClient.Do(req)
+	res, err := c.do("create_job", req)
 	if err != nil {
 		return "", err
 	}
@@ -92,7 +134,7 @@ func (c *cloudManager) GetJob(streamID *big.Int) (*Job, error) {
 		return nil, err
 	}
 
-	res, err := c.httpClient.Do(req)
+	res, err := c.do("get_job", req)
 	if err != nil {
 		return nil, err
 	}
@@ -114,6 +156,8 @@ func (c *cloudManager) GetJob(streamID *big.Int) (*Job, error) {
 		return nil, err
 	}
 
+	metrics.SetJobStatus(job.Status)
+
 	return job, nil
 }
 
@@ -125,7 +169,7 @@ func (c *cloudManager) UpdateJobContractAddress(streamID *big.Int, contractAddre
 		return err
 	}
 
-	res, err := c.httpClient.Do(req)
+	res, err := c.do("update_job_contract_address", req)
 	if err != nil {
 		return err
 	}
@@ -147,7 +191,7 @@ func (c *cloudManager) CancelJob(streamID *big.Int) error {
 		return err
 	}
 
-	res, err := c.httpClient.Do(req)
+	res, err := c.do("cancel_job", req)
 	if err != nil {
 		return err
 	}
@@ -161,25 +205,95 @@ func (c *cloudManager) CancelJob(streamID *big.Int) error {
 	return nil
 }
 
-func (c *cloudManager) AwaitJobStatus(streamID *big.Int, status proto.WorkOrderStatus) (*Job, error) {
-	for timeout := time.After(time.Minute); ; {
-		select {
-		case <-timeout:
-			return nil, errors.New("request timed out")
-		default:
+func (c *cloudManager) AwaitJobStatus(ctx context.Context, streamID *big.Int, status proto.WorkOrderStatus) (*Job, error) {
+	return c.awaitJob(ctx, streamID, func(job *Job) bool {
+		return job.Status == status.String()
+	})
+}
+
+func (c *cloudManager) AwaitJobTerminated(ctx context.Context, streamID *big.Int) (*Job, error) {
+	return c.awaitJob(ctx, streamID, func(job *Job) bool {
+		return job.IsTerminated()
+	})
+}
+
+// WatchJob sends the job on every status change and closes the result
+// channel once the job is terminated or ctx is done. Failed requests are
+// retried with a growing delay, the error is sent after watchMaxFailures
+// requests in a row failed.
+func (c *cloudManager) WatchJob(ctx context.Context, streamID *big.Int) (chan *Job, chan error) {
+	resultCh := make(chan *Job, 1)
+	errCh := make(chan error, 1)
+
+	go func() {
+		status := ""
+		failures := 0
+		for {
+			delay := watchInterval
+
 			job, err := c.GetJob(streamID)
 			if err != nil {
-				return nil, err
+				failures++
+				if failures >= watchMaxFailures {
+					errCh <- fmt.Errorf("failed to get job %d times in a row: %s", failures, err.Error())
+					return
+				}
+
+				delay = watchInterval << uint(failures)
+				if delay > watchMaxDelay {
+					delay = watchMaxDelay
+				}
+				c.logger.WithError(err).WithField("retry_in", delay).Warn("failed to get job")
+			} else {
+				failures = 0
+
+				if job.Status != status {
+					status = job.Status
+					select {
+					case resultCh <- job:
+					case <-ctx.Done():
+						close(resultCh)
+						return
+					}
+				}
+
+				if job.IsTerminated() {
+					close(resultCh)
+					return
+				}
 			}
 
-			c.logger.Infof("received a job status: %s", job.Status)
-
-			if job.Status != status.String() {
-				time.Sleep(5 * time.Second)
-				continue
+			select {
+			case <-ctx.Done():
+				close(resultCh)
+				return
+			case <-time.After(delay):
 			}
+		}
+	}()
 
+	return resultCh, errCh
+}
+
+func (c *cloudManager) awaitJob(ctx context.Context, streamID *big.Int, match func(*Job) bool) (*Job, error) {
+	for timeout := time.After(time.Minute); ; {
+		job, err := c.GetJob(streamID)
+		if err != nil {
+			return nil, err
+		}
+
+		c.logger.Infof("received a job status: %s", job.Status)
+
+		if match(job) {
 			return job, nil
 		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout:
+			return nil, errors.New("request timed out")
+		case <-time.After(5 * time.Second):
+		}
 	}
 }
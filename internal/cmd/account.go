@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/VideoCoin/cli/internal/key"
+	"github.com/VideoCoin/go-videocoin/accounts/keystore"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var cmdAccount = &cobra.Command{
+	Use:   "account",
+	Short: "Manage accounts",
+}
+
+var cmdAccountNew = &cobra.Command{
+	Use:   "new",
+	Short: "Create a new account file",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return setNewPasswordFlag(cmd)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		fflags := cmd.Flags()
+		logger := c.Logger
+		account, _ := fflags.GetString("account")
+		password, _ := fflags.GetString("password")
+
+		ks := key.NewKeyStore()
+		key, err := ks.NewKey(account, password)
+		if err != nil {
+			fatal(logger.WithError(err), &accountRecord{AccountFile: account}, "failed to create account")
+		}
+
+		printAccount(key.Address.Hex(), account)
+	},
+}
+
+var cmdAccountImport = &cobra.Command{
+	Use:   "import",
+	Short: "Import a raw private key or mnemonic into a new account file",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		fflags := cmd.Flags()
+		privateKeyFile, _ := fflags.GetString("private-key-file")
+		mnemonic, _ := fflags.GetBool("mnemonic")
+
+		if (privateKeyFile == "") == !mnemonic {
+			return fmt.Errorf("either private key file or mnemonic must be set")
+		}
+
+		return setNewPasswordFlag(cmd)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		fflags := cmd.Flags()
+		logger := c.Logger
+		account, _ := fflags.GetString("account")
+		password, _ := fflags.GetString("password")
+		privateKeyFile, _ := fflags.GetString("private-key-file")
+		mnemonic, _ := fflags.GetBool("mnemonic")
+		derivationPath, _ := fflags.GetString("derivation-path")
+
+		ks := key.NewKeyStore()
+
+		var (
+			k   *keystore.Key
+			err error
+		)
+		if mnemonic {
+			var words string
+			words, err = promptMnemonic()
+			if words == "" || err != nil {
+				fatal(logger.WithError(err), &accountRecord{AccountFile: account}, "mnemonic is missed")
+			}
+
+			k, err = ks.ImportMnemonic(account, password, words, derivationPath)
+		} else {
+			k, err = ks.ImportPrivateKeyFile(account, password, privateKeyFile)
+		}
+		if err != nil {
+			fatal(logger.WithError(err), &accountRecord{AccountFile: account}, "failed to import account")
+		}
+
+		printAccount(k.Address.Hex(), account)
+	},
+}
+
+func printAccount(address, account string) {
+	record := &accountRecord{Address: address, AccountFile: account}
+	printRecord(record, func() {
+		fmt.Printf("Address: %s\nAccount file: %s\n", record.Address, record.AccountFile)
+	})
+}
+
+func setNewPasswordFlag(cmd *cobra.Command) error {
+	fflags := cmd.Flags()
+	account, _ := fflags.GetString("account")
+
+	password, err := fflags.GetString("password")
+	if password == "" || err != nil {
+		password, err = promptNewPassword()
+		if password == "" || err != nil {
+			fatal(logrus.WithError(err), &accountRecord{AccountFile: account}, "account password is missed")
+		}
+
+		err = fflags.Set("password", password)
+		if err != nil {
+			fatal(logrus.WithError(err), &accountRecord{AccountFile: account}, "failed to set password flag")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/VideoCoin/cli/internal/emitter"
+	"github.com/VideoCoin/cli/internal/key"
+	"github.com/VideoCoin/go-videocoin/common"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var cmdBalance = &cobra.Command{
+	Use:   "balance",
+	Short: "Show account balance",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		fflags := cmd.Flags()
+		account, _ := fflags.GetString("account")
+		address, _ := fflags.GetString("address")
+
+		if account == "" && address == "" {
+			return fmt.Errorf("either account file or address must be set")
+		}
+
+		if address != "" {
+			if !common.IsHexAddress(address) {
+				return fmt.Errorf("not a valid address: %q", address)
+			}
+			return nil
+		}
+
+		password, err := fflags.GetString("password")
+		if password == "" || err != nil {
+			password, err = promptPassword()
+			if password == "" || err != nil {
+				fatal(logrus.WithError(err), &balanceRecord{}, "account password is missed")
+			}
+
+			err = fflags.Set("password", password)
+			if err != nil {
+				fatal(logrus.WithError(err), &balanceRecord{}, "failed to set password flag")
+			}
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		fflags := cmd.Flags()
+		logger := c.Logger
+		account, _ := fflags.GetString("account")
+		password, _ := fflags.GetString("password")
+		address, _ := fflags.GetString("address")
+
+		// the balance is read from the node, the manager is not needed
+		err := c.ValidateNodeRPC()
+		if err != nil {
+			fatal(logger.WithError(err), &balanceRecord{}, "invalid config")
+		}
+
+		var addr common.Address
+		if address != "" {
+			addr = common.HexToAddress(address)
+		} else {
+			ks := key.NewKeyStore()
+			key, err := ks.ImportKey(account, password)
+			if err != nil {
+				fatal(logger.WithError(err), &balanceRecord{}, "failed to import account")
+			}
+			addr = key.Address
+		}
+
+		balance, err := emitter.GetBalance(c.NodeRPCAddr, addr)
+		if err != nil {
+			fatal(logger.WithError(err), &balanceRecord{Address: addr.Hex()}, "failed to get account balance")
+		}
+
+		deposit, _ := emitter.NewBalance(emitter.StreamDeposit)
+
+		record := &balanceRecord{
+			Address:       addr.Hex(),
+			VDC:           balance.VDC.String(),
+			Wei:           balance.Wei.String(),
+			MinimumVDC:    c.MinVDCBalance,
+			MeetsMinimum:  balance.MeetsMinimum(c.MinVDCBalance),
+			DepositWei:    deposit.Wei.String(),
+			CoversDeposit: balance.CoversDeposit(),
+		}
+
+		printRecord(record, func() {
+			fmt.Printf("Address: %s\n", record.Address)
+			fmt.Printf("Balance: %s VDC (%s wei)\n", record.VDC, record.Wei)
+			fmt.Printf("Minimum balance of %d VDC: %s\n", record.MinimumVDC, balanceCheck(record.MeetsMinimum))
+			fmt.Printf("Stream deposit of %s VDC: %s\n", deposit.VDC.String(), balanceCheck(record.CoversDeposit))
+		})
+	},
+}
+
+func balanceCheck(ok bool) string {
+	if ok {
+		return "ok"
+	}
+
+	return "insufficient"
+}
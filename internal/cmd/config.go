@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/VideoCoin/cli/internal/config"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configFile string
+	network    string
+)
+
+var cmdConfig = &cobra.Command{
+	Use:   "config",
+	Short: "Manage configuration",
+}
+
+var cmdConfigShow = &cobra.Command{
+	Use:   "show",
+	Short: "Show effective configuration merged from flags, environment and config file",
+	Run: func(cmd *cobra.Command, args []string) {
+		record := &configRecord{
+			ConfigFile:      configFile,
+			Network:         network,
+			ManagerAddr:     c.ManagerAddr,
+			NodeRPCAddr:     c.NodeRPCAddr,
+			ContractAddress: c.ContractAddress,
+			MinVDCBalance:   c.MinVDCBalance,
+			StateDir:        c.StateDir,
+			Loglevel:        c.Loglevel,
+		}
+
+		printRecord(record, func() {
+			fmt.Printf("Config file: %s\n", record.ConfigFile)
+			fmt.Printf("Network: %s\n", record.Network)
+			fmt.Printf("Manager address: %s\n", record.ManagerAddr)
+			fmt.Printf("Node RPC address: %s\n", record.NodeRPCAddr)
+			fmt.Printf("Contract address: %s\n", record.ContractAddress)
+			fmt.Printf("Minimum VDC balance: %d\n", record.MinVDCBalance)
+			fmt.Printf("State directory: %s\n", record.StateDir)
+			fmt.Printf("Log level: %s\n", record.Loglevel)
+		})
+	},
+}
+
+// loadConfig merges configuration with precedence flags > environment >
+// config file.
+func loadConfig(cmd *cobra.Command) error {
+	err := envconfig.Process("cli", &c)
+	if err != nil {
+		return err
+	}
+
+	fflags := cmd.Flags()
+
+	configFile, _ = fflags.GetString("config")
+	mustExist := configFile != ""
+	if configFile == "" {
+		configFile, err = config.DefaultFilePath()
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := config.LoadFile(configFile, mustExist)
+	if err != nil {
+		return err
+	}
+
+	network, _ = fflags.GetString("network")
+	if network == "" {
+		network = f.Network
+	}
+
+	err = f.Apply(&c, network)
+	if err != nil {
+		return err
+	}
+
+	flagValues := map[string]*string{
+		"manager-addr":     &c.ManagerAddr,
+		"node-rpc-addr":    &c.NodeRPCAddr,
+		"contract-address": &c.ContractAddress,
+		"loglevel":         &c.Loglevel,
+	}
+	for name, value := range flagValues {
+		if fflags.Changed(name) {
+			*value, _ = fflags.GetString(name)
+		}
+	}
+
+	return c.InitLogger(Build, Version)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	balancePollInterval   = time.Minute
+	jobStatusPollInterval = 30 * time.Second
+)
+
+// pollMetrics calls poll right away and then every interval until ctx is
+// done, failures are logged and polling goes on.
+func pollMetrics(ctx context.Context, interval time.Duration, logger *logrus.Entry, poll func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := poll()
+		if err != nil {
+			logger.WithError(err).Warn("failed to poll metrics")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
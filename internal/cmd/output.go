@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/VideoCoin/cli/internal/probe"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+var output string
+
+func validateOutput() error {
+	switch output {
+	case outputText, outputJSON:
+		return nil
+	default:
+		return fmt.Errorf("not a valid output format: %q, must be %s or %s", output, outputText, outputJSON)
+	}
+}
+
+// messageWriter is where human readable messages go, stdout is reserved
+// for records in json output mode.
+func messageWriter() io.Writer {
+	if output == outputJSON {
+		return os.Stderr
+	}
+
+	return os.Stdout
+}
+
+func messagef(format string, a ...interface{}) {
+	fmt.Fprintf(messageWriter(), format, a...)
+}
+
+func messageln(a ...interface{}) {
+	fmt.Fprintln(messageWriter(), a...)
+}
+
+// printRecord writes the record as a json line in json output mode and
+// calls text otherwise.
+func printRecord(record interface{}, text func()) {
+	if output != outputJSON {
+		text()
+		return
+	}
+
+	err := json.NewEncoder(os.Stdout).Encode(record)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to encode record")
+	}
+}
+
+// failedRecord is the last record of a command that failed.
+type failedRecord interface {
+	setError(err string)
+}
+
+// fatal logs msg and exits, record is printed with the error first in json
+// output mode, so readers of stdout learn why the command ended.
+func fatal(entry *logrus.Entry, record failedRecord, msg string) {
+	err := msg
+	if cause, ok := entry.Data[logrus.ErrorKey].(error); ok {
+		err = fmt.Sprintf("%s: %s", msg, cause.Error())
+	}
+
+	record.setError(err)
+	printRecord(record, func() {})
+
+	entry.Fatal(msg)
+}
+
+type configRecord struct {
+	ConfigFile      string `json:"config_file"`
+	Network         string `json:"network"`
+	ManagerAddr     string `json:"manager_addr"`
+	NodeRPCAddr     string `json:"node_rpc_addr"`
+	ContractAddress string `json:"contract_address"`
+	MinVDCBalance   int    `json:"min_vdc_balance"`
+	StateDir        string `json:"state_dir"`
+	Loglevel        string `json:"loglevel"`
+}
+
+type versionRecord struct {
+	Build   string `json:"build"`
+	Version string `json:"version"`
+}
+
+type accountRecord struct {
+	Address     string `json:"address"`
+	AccountFile string `json:"account_file"`
+	Error       string `json:"error,omitempty"`
+}
+
+func (r *accountRecord) setError(err string) { r.Error = err }
+
+type balanceRecord struct {
+	Address       string `json:"address"`
+	VDC           string `json:"vdc"`
+	Wei           string `json:"wei"`
+	MinimumVDC    int    `json:"minimum_vdc"`
+	MeetsMinimum  bool   `json:"meets_minimum"`
+	DepositWei    string `json:"deposit_wei"`
+	CoversDeposit bool   `json:"covers_deposit"`
+	Error         string `json:"error,omitempty"`
+}
+
+func (r *balanceRecord) setError(err string) { r.Error = err }
+
+type jobRecord struct {
+	StreamID  string `json:"stream_id"`
+	Status    string `json:"status"`
+	Profile   string `json:"profile"`
+	OutputURL string `json:"output_url"`
+	Time      string `json:"time,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (r *jobRecord) setError(err string) { r.Error = err }
+
+type streamRecord struct {
+	Event           string            `json:"event"`
+	StreamID        string            `json:"stream_id"`
+	ContractAddress string            `json:"contract_address,omitempty"`
+	InputURL        string            `json:"input_url,omitempty"`
+	OutputURL       string            `json:"output_url,omitempty"`
+	TxHashes        map[string]string `json:"tx_hashes,omitempty"`
+	Status          string            `json:"status,omitempty"`
+	Error           string            `json:"error,omitempty"`
+	RolledBack      []string          `json:"rolled_back,omitempty"`
+	LeftBehind      []string          `json:"left_behind,omitempty"`
+}
+
+func (r *streamRecord) setError(err string) { r.Error = err }
+
+type probeRecord struct {
+	*probe.Result
+	Profile string        `json:"profile,omitempty"`
+	Issues  []probe.Issue `json:"issues,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+func (r *probeRecord) setError(err string) { r.Error = err }
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+type stage struct {
+	name string
+	run  func() error
+	// rollback undoes a completed stage and describes what was undone,
+	// it is nil for stages that can not be undone.
+	rollback func() (string, error)
+	// leftover describes what a completed stage leaves behind when it
+	// can not be undone.
+	leftover func() string
+	// partial describes what a failed run may have left behind, e.g. a
+	// transaction that was sent but not confirmed, empty when nothing.
+	partial func() string
+	// permanent leftovers, e.g. transactions on chain, are reported but
+	// nothing is left to clean up by resuming.
+	permanent bool
+	// rerun stages hold process state and run again on resume.
+	rerun bool
+}
+
+type rollbackReport struct {
+	RolledBack []string
+	LeftBehind []string
+	// Resumable is set when something left behind is not permanent and
+	// can still be cleaned up by resuming.
+	Resumable bool
+}
+
+type pipeline struct {
+	stages    []*stage
+	completed []*stage
+	failed    *stage
+	resumed   map[string]bool
+	logger    *logrus.Entry
+
+	// OnStageComplete is called after every stage that ran successfully.
+	OnStageComplete func(name string)
+}
+
+func newPipeline(logger *logrus.Entry, stages ...*stage) *pipeline {
+	return &pipeline{
+		stages: stages,
+		logger: logger.WithField("component", "pipeline"),
+	}
+}
+
+// Resume marks stages completed by a previous run, Run skips them unless
+// they have to rerun.
+func (p *pipeline) Resume(names []string) {
+	p.resumed = map[string]bool{}
+	for _, name := range names {
+		p.resumed[name] = true
+	}
+}
+
+// Run executes stages in order and stops on the first failed stage or
+// when ctx is canceled between stages.
+func (p *pipeline) Run(ctx context.Context) error {
+	for _, s := range p.stages {
+		if p.resumed[s.name] && !s.rerun {
+			p.logger.Infof("skipping completed stage %q", s.name)
+			p.completed = append(p.completed, s)
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("interrupted before stage %q: %s", s.name, err.Error())
+		}
+
+		p.logger.Infof("running stage %q", s.name)
+
+		err := s.run()
+		if err != nil {
+			p.failed = s
+			return fmt.Errorf("stage %q failed: %s", s.name, err.Error())
+		}
+
+		p.completed = append(p.completed, s)
+
+		if p.OnStageComplete != nil {
+			p.OnStageComplete(s.name)
+		}
+	}
+
+	return nil
+}
+
+// Rollback undoes completed stages in reverse order, after reporting what
+// the failed stage left behind.
+func (p *pipeline) Rollback() *rollbackReport {
+	report := new(rollbackReport)
+
+	if s := p.failed; s != nil && s.partial != nil {
+		if leftover := s.partial(); leftover != "" {
+			report.LeftBehind = append(report.LeftBehind, leftover)
+			report.Resumable = report.Resumable || !s.permanent
+		}
+	}
+
+	for i := len(p.completed) - 1; i >= 0; i-- {
+		s := p.completed[i]
+
+		if s.rollback == nil {
+			if s.leftover != nil {
+				report.LeftBehind = append(report.LeftBehind, s.leftover())
+				report.Resumable = report.Resumable || !s.permanent
+			}
+			continue
+		}
+
+		p.logger.Infof("rolling back stage %q", s.name)
+
+		undone, err := s.rollback()
+		if err != nil {
+			p.logger.WithError(err).Errorf("failed to roll back stage %q", s.name)
+
+			leftover := s.name
+			if s.leftover != nil {
+				leftover = s.leftover()
+			}
+			report.LeftBehind = append(report.LeftBehind,
+				fmt.Sprintf("%s (rollback failed: %s)", leftover, err.Error()))
+			report.Resumable = true
+			continue
+		}
+
+		report.RolledBack = append(report.RolledBack, undone)
+	}
+
+	p.completed = nil
+	p.failed = nil
+
+	return report
+}
+
+func (r *rollbackReport) Print() {
+	for _, s := range r.RolledBack {
+		messagef("Rolled back: %s\n", s)
+	}
+
+	for _, s := range r.LeftBehind {
+		messagef("Left behind: %s\n", s)
+	}
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestPipelineRollback(t *testing.T) {
+	var calls []string
+
+	p := newPipeline(
+		logrus.NewEntry(logrus.New()),
+		&stage{
+			name: "first",
+			run:  func() error { calls = append(calls, "run first"); return nil },
+			leftover: func() string {
+				return "first leftover"
+			},
+			permanent: true,
+		},
+		&stage{
+			name: "second",
+			run:  func() error { calls = append(calls, "run second"); return nil },
+			rollback: func() (string, error) {
+				calls = append(calls, "rollback second")
+				return "second undone", nil
+			},
+		},
+		&stage{
+			name: "third",
+			run:  func() error { calls = append(calls, "run third"); return errors.New("failed") },
+			rollback: func() (string, error) {
+				calls = append(calls, "rollback third")
+				return "third undone", nil
+			},
+			partial: func() string {
+				return "third partial"
+			},
+			permanent: true,
+		},
+	)
+
+	err := p.Run(context.Background())
+	if err == nil {
+		t.Fatal("Run succeeded, want error")
+	}
+
+	report := p.Rollback()
+
+	want := []string{"run first", "run second", "run third", "rollback second"}
+	if len(calls) != len(want) {
+		t.Fatalf("got calls %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("got calls %v, want %v", calls, want)
+			break
+		}
+	}
+
+	if len(report.RolledBack) != 1 || report.RolledBack[0] != "second undone" {
+		t.Errorf("got rolled back %v, want [second undone]", report.RolledBack)
+	}
+	if len(report.LeftBehind) != 2 || report.LeftBehind[0] != "third partial" || report.LeftBehind[1] != "first leftover" {
+		t.Errorf("got left behind %v, want [third partial first leftover]", report.LeftBehind)
+	}
+	if report.Resumable {
+		t.Errorf("got resumable report with only permanent leftovers")
+	}
+}
+
+func TestPipelineInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ran := false
+	p := newPipeline(
+		logrus.NewEntry(logrus.New()),
+		&stage{
+			name: "first",
+			run:  func() error { ran = true; return nil },
+		},
+	)
+
+	err := p.Run(ctx)
+	if err == nil || ran {
+		t.Errorf("Run on canceled context got err %v and ran %t", err, ran)
+	}
+}
+
+func TestPipelineRollbackFailed(t *testing.T) {
+	p := newPipeline(
+		logrus.NewEntry(logrus.New()),
+		&stage{
+			name: "first",
+			run:  func() error { return nil },
+			rollback: func() (string, error) {
+				return "", errors.New("unreachable")
+			},
+			leftover: func() string {
+				return "first leftover"
+			},
+		},
+	)
+
+	err := p.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed with err: %s", err)
+	}
+
+	report := p.Rollback()
+	if len(report.RolledBack) != 0 || len(report.LeftBehind) != 1 || !report.Resumable {
+		t.Errorf("got report %+v, want one left behind and resumable", report)
+	}
+}
+
+func TestPipelineResume(t *testing.T) {
+	var (
+		ran       []string
+		completed []string
+	)
+
+	p := newPipeline(
+		logrus.NewEntry(logrus.New()),
+		&stage{
+			name: "first",
+			run:  func() error { ran = append(ran, "first"); return nil },
+		},
+		&stage{
+			name:  "second",
+			run:   func() error { ran = append(ran, "second"); return nil },
+			rerun: true,
+		},
+		&stage{
+			name: "third",
+			run:  func() error { ran = append(ran, "third"); return nil },
+		},
+	)
+	p.OnStageComplete = func(name string) {
+		completed = append(completed, name)
+	}
+	p.Resume([]string{"first", "second"})
+
+	err := p.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed with err: %s", err)
+	}
+
+	if len(ran) != 2 || ran[0] != "second" || ran[1] != "third" {
+		t.Errorf("got ran stages %v, want [second third]", ran)
+	}
+
+	if len(completed) != 2 || completed[0] != "second" || completed[1] != "third" {
+		t.Errorf("got completed stages %v, want [second third]", completed)
+	}
+
+	if len(p.completed) != 3 {
+		t.Errorf("got %d completed stages to roll back, want 3", len(p.completed))
+	}
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/VideoCoin/cli/internal/cloud"
+	"github.com/VideoCoin/cli/internal/probe"
+	"github.com/spf13/cobra"
+)
+
+var cmdProbe = &cobra.Command{
+	Use:   "probe <url|file>",
+	Short: "Show source stream details",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := c.Logger
+		window, _ := cmd.Flags().GetDuration("window")
+
+		result, err := probe.Probe(args[0], window)
+		if err != nil {
+			fatal(logger.WithError(err), &probeRecord{}, "failed to probe source")
+		}
+
+		record := &probeRecord{Result: result}
+		if profile, ok := probe.Profiles[cloud.DefaultProfileID]; ok {
+			record.Profile = profile.Name
+			record.Issues = profile.Check(result)
+		}
+
+		printRecord(record, func() {
+			fmt.Printf("Source: %s\n", result.Source)
+			fmt.Printf("Sampled: %s, %s\n", result.Duration, formatBitrate(result.Bitrate))
+			for _, stream := range result.Streams {
+				fmt.Printf("Stream %d: %s\n", stream.Idx, formatStreamInfo(stream))
+			}
+
+			if record.Profile == "" {
+				return
+			}
+
+			if len(record.Issues) == 0 {
+				fmt.Printf("Profile %q: ok\n", record.Profile)
+				return
+			}
+
+			fmt.Printf("Profile %q:\n", record.Profile)
+			for _, issue := range record.Issues {
+				level := "warning"
+				if issue.Fatal {
+					level = "error"
+				}
+				fmt.Printf("  %s: %s\n", level, issue)
+			}
+		})
+	},
+}
+
+func formatStreamInfo(s probe.StreamInfo) string {
+	details := []string{s.Type, s.Codec}
+
+	if s.IsVideo() {
+		if s.Profile != "" {
+			details = append(details, s.Profile, s.LevelString())
+		}
+		details = append(details, fmt.Sprintf("%dx%d", s.Width, s.Height))
+		if s.PixelFormat != "" {
+			details = append(details, s.PixelFormat)
+		}
+	} else {
+		details = append(details, fmt.Sprintf("%d Hz", s.SampleRate), fmt.Sprintf("%d ch", s.Channels))
+		if s.SampleFormat != "" {
+			details = append(details, s.SampleFormat)
+		}
+	}
+
+	stats := []string{formatBitrate(s.Bitrate)}
+	if s.IsVideo() {
+		stats = append(stats, fmt.Sprintf("%.1f fps", s.FPS))
+
+		gop := "gop unknown"
+		if s.KeyFrameInterval > 0 {
+			gop = fmt.Sprintf("gop %.0f frames (%s)", s.GOP, s.KeyFrameInterval)
+		}
+		stats = append(stats, gop)
+	}
+
+	return strings.Join(details, " ") + ", " + strings.Join(stats, ", ")
+}
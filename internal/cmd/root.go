@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"time"
+
 	"github.com/VideoCoin/cli/internal/config"
-	"github.com/kelseyhightower/envconfig"
+	"github.com/VideoCoin/cli/internal/key"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -11,6 +13,14 @@ var rootCmd = &cobra.Command{
 	Use:   "cli",
 	Short: "VideoCoin Network Testnet Client",
 	Long:  "VideoCoin Network Testnet Client",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		err := validateOutput()
+		if err != nil {
+			return err
+		}
+
+		return loadConfig(cmd)
+	},
 }
 
 var c config.Config
@@ -18,27 +28,81 @@ var c config.Config
 func Execute(b, v string) {
 	Build = b
 	Version = v
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", outputText, "output format, text or json")
+	rootCmd.PersistentFlags().String("config", "", "config file path, defaults to $XDG_CONFIG_HOME/videocoin-cli/config.yaml")
+	rootCmd.PersistentFlags().String("network", "", "network of the config file to use")
+	rootCmd.PersistentFlags().String("manager-addr", "", "cloud manager address")
+	rootCmd.PersistentFlags().String("node-rpc-addr", "", "blockchain node rpc address")
+	rootCmd.PersistentFlags().String("contract-address", "", "stream manager contract address")
+	rootCmd.PersistentFlags().String("loglevel", "", "log level")
+
 	rootCmd.AddCommand(cmdVersion)
 
-	err := envconfig.Process("cli", &c)
+	cmdStart.Flags().StringP("account", "a", "", "account file path")
+	err := cmdStart.MarkFlagRequired("account")
 	if err != nil {
-		logrus.Fatal(err)
+		logrus.WithError(err).Panic()
 	}
 
-	err = c.InitLogger(b, v)
+	cmdStart.Flags().StringP("password", "p", "", "private key password")
+	cmdStart.Flags().String("resume", "", "resume a persisted session by stream id, sources with passwords must be passed again")
+	cmdStart.Flags().String("file", "", "stream a local .flv or .mp4 file in real time instead of an rtmp source")
+	cmdStart.Flags().Bool("loop", false, "restart the --file source when it ends")
+	cmdStart.Flags().String("listen", "", "accept an rtmp publish on this address and path instead of pulling a source, e.g. :1935/live/key")
+	cmdStart.Flags().StringSlice("simulcast", nil, "additional rtmp or http-flv destination, may be repeated")
+	cmdStart.Flags().String("record", "", "record the stream to a local .flv or .mp4 file")
+	cmdStart.Flags().Duration("record-segment-duration", 0, "start a new recording segment after this duration")
+	cmdStart.Flags().Int64("record-segment-size", 0, "start a new recording segment after this many bytes")
+	cmdStart.Flags().Int("reconnect-attempts", 5, "reconnect attempts for a lost source or destination connection, 0 disables reconnects")
+	cmdStart.Flags().Duration("reconnect-delay", time.Second, "delay before the first reconnect attempt, doubled on every attempt, zero retries immediately")
+	cmdStart.Flags().Duration("reconnect-max-delay", 30*time.Second, "maximum delay between reconnect attempts")
+	cmdStart.Flags().String("slate", "", "loop this .flv or .mp4 file into the stream while the source is down, its codecs must match the source, it plays until stopped when the source is not reconnected")
+	cmdStart.Flags().Duration("stall-timeout", 10*time.Second, "fail a source over when it delivers no packets for this long, at least 1s, 0 disables stall detection")
+	cmdStart.Flags().Duration("preflight-window", 3*time.Second, "sample the source for this long and check it against the job profile before paying, 0 skips the check")
+	cmdStart.Flags().String("metrics-addr", "", "serve prometheus metrics on this address, e.g. :9090")
+
+	rootCmd.AddCommand(cmdStart)
+
+	cmdBalance.Flags().StringP("account", "a", "", "account file path")
+	cmdBalance.Flags().StringP("password", "p", "", "private key password")
+	cmdBalance.Flags().String("address", "", "account address, no account file needed")
+
+	rootCmd.AddCommand(cmdBalance)
+
+	cmdAccountNew.Flags().StringP("account", "a", "", "account file path to create")
+	err = cmdAccountNew.MarkFlagRequired("account")
 	if err != nil {
-		logrus.Fatal(err)
+		logrus.WithError(err).Panic()
 	}
 
-	cmdStart.Flags().StringP("account", "a", "", "account file path")
-	err = cmdStart.MarkFlagRequired("account")
+	cmdAccountNew.Flags().StringP("password", "p", "", "private key password")
+
+	cmdAccount.AddCommand(cmdAccountNew)
+
+	cmdAccountImport.Flags().StringP("account", "a", "", "account file path to create")
+	err = cmdAccountImport.MarkFlagRequired("account")
 	if err != nil {
 		logrus.WithError(err).Panic()
 	}
 
-	cmdStart.Flags().StringP("password", "p", "", "private key password")
+	cmdAccountImport.Flags().StringP("password", "p", "", "private key password")
+	cmdAccountImport.Flags().String("private-key-file", "", "file with a hex encoded private key")
+	cmdAccountImport.Flags().Bool("mnemonic", false, "prompt for a BIP-39 mnemonic")
+	cmdAccountImport.Flags().String("derivation-path", key.DefaultDerivationPath, "BIP-44 derivation path of the mnemonic key")
 
-	rootCmd.AddCommand(cmdStart)
+	cmdAccount.AddCommand(cmdAccountImport)
+	rootCmd.AddCommand(cmdAccount)
+
+	cmdStatus.Flags().BoolP("watch", "w", false, "poll and print status changes until the job is terminated")
+
+	rootCmd.AddCommand(cmdStatus)
+
+	cmdProbe.Flags().Duration("window", 5*time.Second, "stream time to sample for bitrate, fps and gop length")
+
+	rootCmd.AddCommand(cmdProbe)
+
+	cmdConfig.AddCommand(cmdConfigShow)
+	rootCmd.AddCommand(cmdConfig)
 
 	if err := rootCmd.Execute(); err != nil {
 		logrus.WithError(err).Panic()
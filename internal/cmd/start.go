@@ -1,13 +1,20 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"math/big"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/VideoCoin/common/proto"
 	"github.com/VideoCoin/cli/internal/cloud"
 	"github.com/VideoCoin/cli/internal/emitter"
 	"github.com/VideoCoin/cli/internal/key"
+	"github.com/VideoCoin/cli/internal/metrics"
+	"github.com/VideoCoin/cli/internal/session"
+	"github.com/VideoCoin/cli/internal/source"
 	"github.com/VideoCoin/cli/internal/transmitter"
 	"github.com/briandowns/spinner"
 	"github.com/sirupsen/logrus"
@@ -15,26 +22,45 @@ import (
 )
 
 var cmdStart = &cobra.Command{
-	Use:   "start [rtmp-address]",
+	Use:   "start [source-url] [backup-source-url...]",
 	Short: "start streaming to VideoCoin testnet",
-	Args:  cobra.MinimumNArgs(1),
+	Args: func(cmd *cobra.Command, args []string) error {
+		file, _ := cmd.Flags().GetString("file")
+		listen, _ := cmd.Flags().GetString("listen")
+		if file != "" && listen != "" {
+			return fmt.Errorf("file and listen sources can not be combined")
+		}
+		if file != "" || listen != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+
+		resume, _ := cmd.Flags().GetString("resume")
+		if resume != "" {
+			return cobra.ArbitraryArgs(cmd, args)
+		}
+
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		fflags := cmd.Flags()
 		account, err := fflags.GetString("account")
 		if account == "" || err != nil {
-			logrus.WithError(err).Fatal("account file is missed")
+			fatal(logrus.WithError(err), &streamRecord{Event: "failed"}, "account file is missed")
 		}
 
 		password, err := fflags.GetString("password")
+		if (password == "" || err != nil) && len(args) > 0 && args[0] == source.Stdin {
+			fatal(logrus.NewEntry(logrus.StandardLogger()), &streamRecord{Event: "failed"}, "account password must be passed with --password when streaming stdin")
+		}
 		if password == "" || err != nil {
 			password, err = promptPassword()
 			if password == "" || err != nil {
-				logrus.WithError(err).Fatal("account password is missed")
+				fatal(logrus.WithError(err), &streamRecord{Event: "failed"}, "account password is missed")
 			}
 
 			err = fflags.Set("password", password)
 			if err != nil {
-				logrus.WithError(err).Fatal("failed to set password flag")
+				fatal(logrus.WithError(err), &streamRecord{Event: "failed"}, "failed to set password flag")
 			}
 		}
 
@@ -45,21 +71,176 @@ var cmdStart = &cobra.Command{
 		logger := c.Logger
 		account, _ := fflags.GetString("account")
 		password, _ := fflags.GetString("password")
+		resume, _ := fflags.GetString("resume")
+		file, _ := fflags.GetString("file")
+		loop, _ := fflags.GetBool("loop")
+		listen, _ := fflags.GetString("listen")
+		simulcast, _ := fflags.GetStringSlice("simulcast")
+		recordPath, _ := fflags.GetString("record")
+		recordSegmentDuration, _ := fflags.GetDuration("record-segment-duration")
+		recordSegmentSize, _ := fflags.GetInt64("record-segment-size")
+		reconnectAttempts, _ := fflags.GetInt("reconnect-attempts")
+		reconnectDelay, _ := fflags.GetDuration("reconnect-delay")
+		reconnectMaxDelay, _ := fflags.GetDuration("reconnect-max-delay")
+		stallTimeout, _ := fflags.GetDuration("stall-timeout")
+		slate, _ := fflags.GetString("slate")
+		metricsAddr, _ := fflags.GetString("metrics-addr")
+		preflightWindow, _ := fflags.GetDuration("preflight-window")
+
+		sess := new(session.Session)
+		// fail ends start before the pipeline runs with a failed record
+		fail := func(entry *logrus.Entry, msg string) {
+			fatal(entry, newStreamRecord("failed", sess), msg)
+		}
 
-		sourceRtmpUrl := args[0]
-		err := probeConnection(sourceRtmpUrl)
+		err := c.Validate()
 		if err != nil {
-			logger.WithError(err).Fatal("failed to probe input rtmp url")
+			fail(logger.WithError(err), "invalid config")
+		}
+
+		if stallTimeout < 0 || (stallTimeout > 0 && stallTimeout < transmitter.MinStallTimeout) {
+			fail(logger, fmt.Sprintf("invalid stall timeout: %s, must be 0 or at least %s", stallTimeout, transmitter.MinStallTimeout))
+		}
+
+		var record *transmitter.RecordConfig
+		if recordPath != "" {
+			record = &transmitter.RecordConfig{
+				Path:            recordPath,
+				SegmentDuration: recordSegmentDuration,
+				SegmentSize:     recordSegmentSize,
+			}
+
+			err = record.Validate()
+			if err != nil {
+				fail(logger.WithError(err), "invalid recording")
+			}
+		}
+
+		store, err := newSessionStore()
+		if err != nil {
+			fail(logger.WithError(err), "failed to open session store")
+		}
+
+		if resume != "" {
+			loaded, err := store.Load(resume)
+			if err != nil {
+				fail(logger.WithError(err), "failed to load session")
+			}
+			sess = loaded
+
+			messagef("Resuming stream %s, completed stages: %s.\n",
+				sess.StreamID, strings.Join(sess.CompletedStages, ", "))
+		}
+
+		src, backups := sess.Source, sess.Backups
+		if len(args) > 0 {
+			src, backups = args[0], args[1:]
+			sess.SourceFile = false
+			sess.SourceListen = false
+		}
+		if file != "" {
+			src, backups = file, nil
+			sess.SourceFile = true
+			sess.SourceListen = false
+		}
+		if listen != "" {
+			src, backups = listen, nil
+			sess.SourceFile = false
+			sess.SourceListen = true
+		}
+		if len(args) == 0 && file == "" && listen == "" && sess.SourceRedacted {
+			fail(logger, "source credentials are not saved in the session, pass the sources again to resume")
+		}
+
+		// passwords are never written to the session file
+		sess.Source = source.Redact(src)
+		sess.Backups = nil
+		sess.SourceRedacted = sess.Source != src
+		for _, backup := range backups {
+			redacted := source.Redact(backup)
+			sess.Backups = append(sess.Backups, redacted)
+			sess.SourceRedacted = sess.SourceRedacted || redacted != backup
+		}
+
+		// nothing needs to be cleaned up before the pipeline runs, ctx is
+		// checked after every step that waits
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sigs := exitSignal()
+		go func() {
+			sig := <-sigs
+			messageln()
+			messageln(sig)
+			messageln("Shutting down, press cmd+c again to force exit.")
+			cancel()
+
+			forceExitOnSignal(sigs)
+		}()
+
+		var ingest *transmitter.Ingest
+		if sess.SourceListen {
+			ingest, err = transmitter.NewIngest(src, logrus.NewEntry(logger.Logger))
+			if err != nil {
+				fail(logger.WithError(err), "invalid listen address")
+			}
+
+			ingest.Listen()
+			defer ingest.Close()
+
+			messagef("Waiting for a publisher on %s...\n", ingest.URL())
+			err = ingest.Wait(ctx.Done())
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				fail(logger.WithError(err), "failed to accept a publisher")
+			}
+		}
+
+		switch {
+		case sess.SourceListen:
+			// the publisher is relayed as is, it can not be sampled before
+		case source.Once(src):
+			// probing would consume the only read of the source
+		case preflightWindow > 0:
+			err = preflight(src, preflightWindow)
+			if err != nil {
+				fail(logger.WithError(err), "source failed pre-flight check")
+			}
+		case sess.SourceFile:
+			_, err = os.Stat(src)
+			if err != nil {
+				fail(logger.WithError(err), "failed to open source file")
+			}
+		default:
+			err = probeConnection(src)
+			if err != nil {
+				fail(logger.WithError(err), "failed to probe input rtmp url")
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if metricsAddr != "" {
+			err = metrics.Serve(metricsAddr, logger)
+			if err != nil {
+				fail(logger.WithError(err), "failed to serve metrics")
+			}
+			logger.Infof("serving metrics on %s", metricsAddr)
 		}
 
 		spinner := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
+		spinner.Writer = messageWriter()
 		spinner.Start()
 		defer spinner.Stop()
 
 		ks := key.NewKeyStore()
 		key, err := ks.ImportKey(account, password)
 		if err != nil {
-			logger.WithError(err).Fatal("failed to import account")
+			fail(logger.WithError(err), "failed to import account")
 		}
 
 		em, err := emitter.NewEmitterManager(
@@ -71,18 +252,35 @@ var cmdStart = &cobra.Command{
 			},
 		)
 		if err != nil {
-			logger.WithError(err).Fatal("failed to create a stream manager")
+			fail(logger.WithError(err), "failed to create a stream manager")
 		}
 
 		balance, err := em.GetAddressBalance()
 		if err != nil {
-			logger.WithError(err).Fatal("failed to get account balance")
+			fail(logger.WithError(err), "failed to get account balance")
 		}
 
 		fbalance, _ := balance.Float64()
 		if fbalance < float64(c.MinVDCBalance) {
-			logger.WithField("balance", fbalance).Fatalf(
-				"insufficient account balance, must be minimum %d VDC available", c.MinVDCBalance)
+			fail(logger.WithField("balance", fbalance), fmt.Sprintf(
+				"insufficient account balance, must be minimum %d VDC available", c.MinVDCBalance))
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if metricsAddr != "" {
+			go pollMetrics(ctx, balancePollInterval, logger, func() error {
+				balance, err := em.GetAddressBalance()
+				if err != nil {
+					return err
+				}
+
+				fbalance, _ := balance.Float64()
+				metrics.AccountBalance.Set(fbalance)
+				return nil
+			})
 		}
 
 		cm := cloud.NewCloudManager(
@@ -92,66 +290,319 @@ var cmdStart = &cobra.Command{
 			},
 		)
 
-		streamID, err := em.RequestStream()
-		if err != nil {
-			logger.WithError(err).Fatal("failed to request stream")
-		}
+		var (
+			streamID           *big.Int
+			destinationRtmpUrl string
+			contractAddress    string
+			job                *cloud.Job
+			transmitterErrCh   <-chan error
+			stopTransmitter    context.CancelFunc
+		)
 
-		logger.Infof("acquired stream id %s", streamID.String())
+		if resume != "" {
+			var ok bool
+			streamID, ok = new(big.Int).SetString(sess.StreamID, 10)
+			if !ok {
+				fail(logger.WithField("stream_id", sess.StreamID), "invalid stream id in session")
+			}
+			destinationRtmpUrl = sess.Destination
+			contractAddress = sess.ContractAddress
 
-		destinationRtmpUrl, err := cm.CreateJob(streamID, key.Address.String())
-		if err != nil {
-			logger.WithError(err).Fatal("failed to create job")
+			// a terminated job can not become ready again
+			if sess.Destination != "" {
+				job, err := cm.GetJob(streamID)
+				if err != nil {
+					fail(logger.WithError(err), "failed to get job")
+				}
+				if job.IsTerminated() {
+					if err := store.Remove(sess.StreamID); err != nil {
+						logger.WithError(err).Warn("failed to remove session")
+					}
+					fail(logger.WithField("status", job.Status), "job of the session is terminated, nothing to resume")
+				}
+			}
 		}
 
-		logger.Infof("acquired destination rtmp url %s", destinationRtmpUrl)
-
-		_, err = cm.AwaitJobStatus(streamID, proto.WorkOrderStatusApproved)
-		if err != nil {
-			logger.WithError(err).Fatal("failed to get approved job")
+		tc := transmitter.TransmitterConfig{
+			Source:       src,
+			Backups:      backups,
+			StallTimeout: stallTimeout,
+			Slate:        slate,
+			File:         sess.SourceFile,
+			Loop:         loop,
+			Ingest:       ingest,
+			Simulcast:    simulcast,
+			Record:       record,
+			Reconnect: transmitter.ReconnectConfig{
+				MaxAttempts:  reconnectAttempts,
+				InitialDelay: reconnectDelay,
+				MaxDelay:     reconnectMaxDelay,
+			},
+			Logger: logrus.NewEntry(logger.Logger),
 		}
+		var t *transmitter.Transmitter
 
-		logger.Infof("acquired approved job")
+		p := newPipeline(
+			logger,
+			&stage{
+				name: "request stream",
+				run: func() error {
+					var txHash string
+					streamID, txHash, err = em.RequestStream(ctx)
+					if err != nil {
+						return err
+					}
 
-		contractAddress, err := em.CreateStream(streamID)
-		if err != nil {
-			logger.WithError(err).Fatal("failed to create stream")
-		}
+					sess.StreamID = streamID.String()
+					sess.SetTxHash("request stream", txHash)
 
-		logger.Infof("acquired stream address %s", contractAddress)
+					logger.Infof("acquired stream id %s", streamID.String())
+					return nil
+				},
+				leftover: func() string {
+					return fmt.Sprintf("stream request %s on chain", streamID.String())
+				},
+				permanent: true,
+			},
+			&stage{
+				name: "create job",
+				run: func() error {
+					destinationRtmpUrl, err = cm.CreateJob(streamID, key.Address.String())
+					if err != nil {
+						return err
+					}
 
-		err = cm.UpdateJobContractAddress(streamID, contractAddress)
-		if err != nil {
-			logger.WithError(err).Fatal("failed to update job")
-		}
+					sess.Destination = destinationRtmpUrl
 
-		tc := transmitter.TransmitterConfig{
-			Source:      sourceRtmpUrl,
-			Destination: destinationRtmpUrl,
-			Logger:      logrus.NewEntry(logger.Logger),
-		}
-		transmitter := transmitter.NewTransmitter(tc)
+					logger.Infof("acquired destination rtmp url %s", destinationRtmpUrl)
+					return nil
+				},
+				rollback: func() (string, error) {
+					err := cm.CancelJob(streamID)
+					if err != nil {
+						return "", err
+					}
 
-		go func() {
-			err := transmitter.Start()
+					// rollbacks run once ctx is canceled
+					job, err := cm.AwaitJobTerminated(context.Background(), streamID)
+					if err != nil {
+						return "", err
+					}
+
+					sess.JobStatus = job.Status
+
+					return fmt.Sprintf("job of stream %s is %s", streamID.String(), job.Status), nil
+				},
+				leftover: func() string {
+					return fmt.Sprintf("job of stream %s on %s", streamID.String(), c.ManagerAddr)
+				},
+			},
+			&stage{
+				name: "await approved job",
+				run: func() error {
+					_, err := cm.AwaitJobStatus(ctx, streamID, proto.WorkOrderStatusApproved)
+					if err != nil {
+						return err
+					}
+
+					logger.Infof("acquired approved job")
+					return nil
+				},
+			},
+			&stage{
+				name: "create stream",
+				run: func() error {
+					var txHash string
+					contractAddress, txHash, err = em.CreateStream(ctx, streamID)
+					if txHash != "" {
+						sess.SetTxHash("create stream", txHash)
+					}
+					if err != nil {
+						return err
+					}
+
+					sess.ContractAddress = contractAddress
+
+					logger.Infof("acquired stream address %s", contractAddress)
+					return nil
+				},
+				leftover: func() string {
+					return fmt.Sprintf("stream contract %s holding the deposit", contractAddress)
+				},
+				partial: func() string {
+					txHash := sess.TxHashes["create stream"]
+					if txHash == "" {
+						return ""
+					}
+					return fmt.Sprintf("create stream transaction %s, its deposit may be held", txHash)
+				},
+				// the contract stays, resuming can not act on it once the
+				// job is terminal
+				permanent: true,
+			},
+			&stage{
+				name: "update job contract address",
+				run: func() error {
+					return cm.UpdateJobContractAddress(streamID, contractAddress)
+				},
+			},
+			&stage{
+				name: "start transmitter",
+				run: func() error {
+					tc.Destination = destinationRtmpUrl
+					t = transmitter.NewTransmitter(tc)
+
+					if metricsAddr != "" {
+						err := metrics.RegisterTransmitter(t.Stats)
+						if err != nil {
+							logger.WithError(err).Warn("failed to register transmitter metrics")
+						}
+					}
+
+					// the transmitter is stopped by its rollback rather than by
+					// ctx, so it is flushed before the job is canceled
+					var tctx context.Context
+					tctx, stopTransmitter = context.WithCancel(context.Background())
+					transmitterErrCh = t.Start(tctx)
+
+					return nil
+				},
+				rollback: func() (string, error) {
+					stopTransmitter()
+					for err := range transmitterErrCh {
+						if err != nil {
+							logger.WithError(err).Warn("transmitter failed")
+						}
+					}
+
+					for _, stats := range t.DestinationStats() {
+						logger.WithFields(logrus.Fields{
+							"packets":      stats.Packets,
+							"bytes":        stats.Bytes,
+							"dropped":      stats.Dropped,
+							"dropped_gops": stats.DroppedGOPs,
+							"reconnects":   stats.Reconnects,
+							"error":        stats.Error,
+						}).Infof("transmitted to %s", stats.URL)
+					}
+
+					return "transmitter stopped", nil
+				},
+				rerun: true,
+			},
+			&stage{
+				name: "await ready job",
+				run: func() error {
+					job, err = cm.AwaitJobStatus(ctx, streamID, proto.WorkOrderStatusReady)
+					if err != nil {
+						return err
+					}
+
+					sess.JobStatus = job.Status
+					sess.OutputURL = job.OutputURL
+					return nil
+				},
+				rerun: true,
+			},
+		)
+		p.OnStageComplete = func(name string) {
+			sess.CompleteStage(name)
+
+			err := store.Save(sess)
 			if err != nil {
-				logger.WithError(err).Fatal("failed to start transmitter")
+				logger.WithError(err).Warn("failed to save session")
+			}
+		}
+		p.Resume(sess.CompletedStages)
+
+		rollback := func(event string, cause error) {
+			report := p.Rollback()
+
+			// the session is kept while a resume can act on what was left
+			// behind, i.e. the job is still live
+			if sess.StreamID != "" && !report.Resumable {
+				err := store.Remove(sess.StreamID)
+				if err != nil {
+					logger.WithError(err).Warn("failed to remove session")
+				}
+			} else if sess.StreamID != "" {
+				// keeps what the rollback and a failed stage recorded
+				err := store.Save(sess)
+				if err != nil {
+					logger.WithError(err).Warn("failed to save session")
+				}
+				messagef("Session of stream %s was kept, resume it with --resume %s.\n", sess.StreamID, sess.StreamID)
 			}
-		}()
 
-		job, err := cm.AwaitJobStatus(streamID, proto.WorkOrderStatusReady)
+			record := newStreamRecord(event, sess)
+			record.RolledBack = report.RolledBack
+			record.LeftBehind = report.LeftBehind
+			if cause != nil {
+				record.Error = cause.Error()
+			}
+
+			printRecord(record, report.Print)
+		}
+
+		err = p.Run(ctx)
 		if err != nil {
-			logger.WithError(err).Fatal("failed to get ready job")
+			spinner.Stop()
+
+			// ctrl+c interrupted a stage, it is a requested shutdown
+			if ctx.Err() != nil {
+				rollback("stopped", nil)
+				return
+			}
+
+			rollback("failed", err)
+			logger.WithError(err).Fatal("failed to start stream")
 		}
 
 		spinner.Stop()
-		fmt.Printf(
-			"Your stream is going to be available shortly. Use next URL to access it: %s\n", job.OutputURL)
+		printRecord(newStreamRecord("ready", sess), func() {
+			fmt.Printf(
+				"Your stream is going to be available shortly. Use next URL to access it: %s\n", job.OutputURL)
+			fmt.Printf("Stream ID: %s\n", sess.StreamID)
+		})
+
+		messageln("Stop streaming with cmd+c.")
 
-		fmt.Println("Stop streaming with cmd+c.")
+		stopStats := renderStats(t)
 
-		done := exitSignal()
-		<-done
-		transmitter.Stop()
+		if metricsAddr != "" {
+			go pollMetrics(ctx, jobStatusPollInterval, logger, func() error {
+				// GetJob updates the job status gauge
+				_, err := cm.GetJob(streamID)
+				return err
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			stopStats()
+			rollback("stopped", nil)
+		case err := <-transmitterErrCh:
+			stopStats()
+			if err == nil {
+				messageln("Source stream ended, shutting down.")
+				rollback("stopped", nil)
+				return
+			}
+
+			rollback("failed", err)
+			logger.WithError(err).Fatal("failed to transmit stream")
+		}
 	},
 }
+
+func newStreamRecord(event string, sess *session.Session) *streamRecord {
+	return &streamRecord{
+		Event:           event,
+		StreamID:        sess.StreamID,
+		ContractAddress: sess.ContractAddress,
+		InputURL:        sess.Destination,
+		OutputURL:       sess.OutputURL,
+		TxHashes:        sess.TxHashes,
+		Status:          sess.JobStatus,
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/VideoCoin/cli/internal/transmitter"
+)
+
+// renderStats rewrites a single line with transmitter stats every second
+// until the returned stop function is called.
+func renderStats(t *transmitter.Transmitter) func() {
+	if output != outputText {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				messageln()
+				return
+			case <-ticker.C:
+				messagef("\r\033[K%s", formatStats(t.Stats()))
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func formatStats(stats transmitter.Stats) string {
+	lastKeyFrame := "never"
+	if !stats.LastKeyFrame.IsZero() {
+		lastKeyFrame = time.Since(stats.LastKeyFrame).Truncate(100*time.Millisecond).String() + " ago"
+	}
+
+	line := fmt.Sprintf(
+		"%s (avg %s), %.1f fps, %d keyframes, last %s, write latency %s, %d reconnects, %d dropped",
+		formatBitrate(stats.Bitrate), formatBitrate(stats.AverageBitrate), stats.FPS,
+		stats.KeyFrames, lastKeyFrame, stats.WriteLatency.Truncate(time.Microsecond), stats.Reconnects,
+		stats.Dropped)
+
+	if stats.Slate {
+		line += ", playing slate"
+	}
+
+	if stats.ActiveSource > 0 {
+		line += fmt.Sprintf(", on backup source %d", stats.ActiveSource)
+	}
+
+	return line
+}
+
+func formatBitrate(bps float64) string {
+	switch {
+	case bps >= 1e6:
+		return fmt.Sprintf("%.2f Mbps", bps/1e6)
+	case bps >= 1e3:
+		return fmt.Sprintf("%.1f kbps", bps/1e3)
+	default:
+		return fmt.Sprintf("%.0f bps", bps)
+	}
+}
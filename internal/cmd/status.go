@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/VideoCoin/cli/internal/cloud"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var cmdStatus = &cobra.Command{
+	Use:   "status [stream-id]",
+	Short: "Show stream job status",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		fflags := cmd.Flags()
+		logger := c.Logger
+		watch, _ := fflags.GetBool("watch")
+
+		err := c.Validate()
+		if err != nil {
+			fatal(logger.WithError(err), &jobRecord{StreamID: args[0]}, "invalid config")
+		}
+
+		streamID, ok := new(big.Int).SetString(args[0], 10)
+		if !ok {
+			fatal(logger, &jobRecord{StreamID: args[0]}, fmt.Sprintf("not a valid stream id: %q", args[0]))
+		}
+
+		cm := cloud.NewCloudManager(
+			cloud.CloudManagerConfig{
+				ManagerAddr: c.ManagerAddr,
+				Logger:      logrus.NewEntry(logger.Logger),
+			},
+		)
+
+		if !watch {
+			job, err := cm.GetJob(streamID)
+			if err != nil {
+				fatal(logger.WithError(err), &jobRecord{StreamID: args[0]}, "failed to get job")
+			}
+
+			record := newJobRecord(streamID, job)
+			printRecord(record, func() {
+				fmt.Printf("Stream: %s\nStatus: %s\nProfile: %s\nOutput URL: %s\n",
+					record.StreamID, record.Status, record.Profile, record.OutputURL)
+			})
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sigs := exitSignal()
+		go func() {
+			<-sigs
+			cancel()
+
+			forceExitOnSignal(sigs)
+		}()
+
+		resultCh, errCh := cm.WatchJob(ctx, streamID)
+		for {
+			select {
+			case err := <-errCh:
+				fatal(logger.WithError(err), &jobRecord{StreamID: args[0]}, "failed to watch job")
+			case job, ok := <-resultCh:
+				if !ok {
+					return
+				}
+
+				record := newJobRecord(streamID, job)
+				record.Time = time.Now().Format(time.RFC3339)
+				printRecord(record, func() {
+					fmt.Printf("%s %s profile=%s output=%s\n",
+						record.Time, record.Status, record.Profile, record.OutputURL)
+				})
+			}
+		}
+	},
+}
+
+func newJobRecord(streamID *big.Int, job *cloud.Job) *jobRecord {
+	return &jobRecord{
+		StreamID:  streamID.String(),
+		Status:    job.Status,
+		Profile:   job.Profile,
+		OutputURL: job.OutputURL,
+	}
+}
@@ -5,13 +5,17 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	"github.com/nareix/joy4/av/avutil"
+	"github.com/VideoCoin/cli/internal/cloud"
+	"github.com/VideoCoin/cli/internal/probe"
+	"github.com/VideoCoin/cli/internal/session"
+	"github.com/VideoCoin/cli/internal/source"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
 func promptPassword() (string, error) {
-	fmt.Println("Enter account password:")
+	messageln("Enter account password:")
 	b, err := terminal.ReadPassword(int(syscall.Stdin))
 	if err != nil {
 		return "", err
@@ -20,12 +24,41 @@ func promptPassword() (string, error) {
 	return string(b), nil
 }
 
-func probeConnection(source string) error {
-	if source == "" {
+func promptNewPassword() (string, error) {
+	password, err := promptPassword()
+	if err != nil {
+		return "", err
+	}
+
+	messageln("Repeat account password:")
+	b, err := terminal.ReadPassword(int(syscall.Stdin))
+	if err != nil {
+		return "", err
+	}
+
+	if string(b) != password {
+		return "", fmt.Errorf("passwords do not match")
+	}
+
+	return password, nil
+}
+
+func promptMnemonic() (string, error) {
+	messageln("Enter mnemonic:")
+	b, err := terminal.ReadPassword(int(syscall.Stdin))
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+func probeConnection(uri string) error {
+	if uri == "" {
 		return fmt.Errorf("source stream is not set")
 	}
 
-	conn, err := avutil.Open(source)
+	conn, err := source.Open(uri)
 	if err != nil {
 		return fmt.Errorf("failed to open source connection: %s", err.Error())
 	}
@@ -39,18 +72,64 @@ func probeConnection(source string) error {
 	return nil
 }
 
-func exitSignal() chan bool {
-	sigs := make(chan os.Signal, 1)
-	done := make(chan bool, 1)
+// preflight samples the source for window and checks it against the
+// profile jobs are created with, warnings are printed and fatal issues
+// refuse the source.
+func preflight(source string, window time.Duration) error {
+	messagef("Checking source for %s...\n", window)
+
+	result, err := probe.Probe(source, window)
+	if err != nil {
+		return err
+	}
+
+	profile, ok := probe.Profiles[cloud.DefaultProfileID]
+	if !ok {
+		return nil
+	}
+
+	fatal := 0
+	for _, issue := range profile.Check(result) {
+		if issue.Fatal {
+			fatal++
+			messagef("Error: %s\n", issue)
+			continue
+		}
 
+		messagef("Warning: %s\n", issue)
+	}
+
+	if fatal > 0 {
+		return fmt.Errorf("source is not compatible with profile %q", profile.Name)
+	}
+
+	return nil
+}
+
+func newSessionStore() (*session.Store, error) {
+	dir := c.StateDir
+	if dir == "" {
+		var err error
+		dir, err = session.DefaultDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return session.NewStore(dir), nil
+}
+
+func exitSignal() chan os.Signal {
+	sigs := make(chan os.Signal, 2)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 
-	go func() {
-		sig := <-sigs
-		fmt.Println()
-		fmt.Println(sig)
-		done <- true
-	}()
+	return sigs
+}
 
-	return done
+func forceExitOnSignal(sigs chan os.Signal) {
+	sig := <-sigs
+	messageln()
+	messageln(sig)
+	messageln("Forced exit, the stream may not be cleaned up.")
+	os.Exit(1)
 }
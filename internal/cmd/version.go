@@ -15,6 +15,8 @@ var cmdVersion = &cobra.Command{
 	Use:   "version",
 	Short: "Show build and version",
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("Build: %s\nVersion: %s\n", Build, Version)
+		printRecord(&versionRecord{Build: Build, Version: Version}, func() {
+			fmt.Printf("Build: %s\nVersion: %s\n", Build, Version)
+		})
 	},
 }
@@ -2,22 +2,52 @@ package config
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/sirupsen/logrus"
 	prefixed "github.com/x-cray/logrus-prefixed-formatter"
 )
 
 type Config struct {
-	ManagerAddr     string `required:"true" default:""`
-	NodeRPCAddr     string `required:"true" envconfig:"NodeRPCAddr" default:""`
-	ContractAddress string `required:"true" envconfig:"ContractAddress" default:"" description:"stream manager contract address"`
+	ManagerAddr     string `default:""`
+	NodeRPCAddr     string `envconfig:"NodeRPCAddr" default:""`
+	ContractAddress string `envconfig:"ContractAddress" default:"" description:"stream manager contract address"`
 
 	MinVDCBalance int `required:"true" default:"15"`
 
+	StateDir string `envconfig:"STATEDIR" default:"" description:"directory of persisted stream sessions"`
+
 	Logger   *logrus.Entry `ignored:"true"`
 	Loglevel string        `default:"FATAL" envconfig:"LOGLEVEL"`
 }
 
+// Validate checks the addresses every network command depends on, they may
+// come from the environment, the config file or flags.
+func (c *Config) Validate() error {
+	if c.ManagerAddr == "" {
+		return fmt.Errorf("manager address is not set")
+	}
+
+	if err := c.ValidateNodeRPC(); err != nil {
+		return err
+	}
+
+	if c.ContractAddress == "" {
+		return fmt.Errorf("contract address is not set")
+	}
+
+	return nil
+}
+
+// ValidateNodeRPC checks the config of commands that only read the chain.
+func (c *Config) ValidateNodeRPC() error {
+	if c.NodeRPCAddr == "" {
+		return fmt.Errorf("node rpc address is not set")
+	}
+
+	return nil
+}
+
 func (c *Config) InitLogger(name, version string) error {
 	level, err := logrus.ParseLevel(c.Loglevel)
 	if err != nil {
@@ -39,6 +69,7 @@ func (c *Config) InitLogger(name, version string) error {
 	})
 
 	logger.Logger.Formatter = formatter
+	logger.Logger.Out = os.Stderr
 	c.Logger = logger
 
 	return nil
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+type Network struct {
+	ManagerAddr     string `yaml:"manager_addr"`
+	NodeRPCAddr     string `yaml:"node_rpc_addr"`
+	ContractAddress string `yaml:"contract_address"`
+	MinVDCBalance   int    `yaml:"min_vdc_balance"`
+}
+
+type File struct {
+	// Network is used when no network is selected with a flag.
+	Network  string             `yaml:"network"`
+	Networks map[string]Network `yaml:"networks"`
+	Loglevel string             `yaml:"loglevel"`
+	StateDir string             `yaml:"state_dir"`
+}
+
+// DefaultFilePath returns $XDG_CONFIG_HOME/videocoin-cli/config.yaml,
+// falling back to ~/.config when XDG_CONFIG_HOME is not set.
+func DefaultFilePath() (string, error) {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %s", err.Error())
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(configHome, "videocoin-cli", "config.yaml"), nil
+}
+
+// LoadFile reads a config file, a missing file is only an error when
+// mustExist is set.
+func LoadFile(path string, mustExist bool) (*File, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !mustExist {
+			return new(File), nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %s", err.Error())
+	}
+
+	f := new(File)
+	err = yaml.UnmarshalStrict(b, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %s", path, err.Error())
+	}
+
+	return f, nil
+}
+
+// Apply sets values of the selected network on c, values set in the
+// environment take precedence over the file.
+func (f *File) Apply(c *Config, network string) error {
+	if network == "" {
+		network = f.Network
+	}
+
+	if network != "" {
+		n, ok := f.Networks[network]
+		if !ok {
+			return fmt.Errorf("network %q is not defined in config file", network)
+		}
+
+		setString(&c.ManagerAddr, n.ManagerAddr, "MANAGERADDR")
+		setString(&c.NodeRPCAddr, n.NodeRPCAddr, "NODERPCADDR")
+		setString(&c.ContractAddress, n.ContractAddress, "CONTRACTADDRESS")
+		if n.MinVDCBalance != 0 && !envSet("MINVDCBALANCE") {
+			c.MinVDCBalance = n.MinVDCBalance
+		}
+	}
+
+	setString(&c.Loglevel, f.Loglevel, "LOGLEVEL")
+	setString(&c.StateDir, f.StateDir, "STATEDIR")
+
+	return nil
+}
+
+func setString(dst *string, value, key string) {
+	if value != "" && !envSet(key) {
+		*dst = value
+	}
+}
+
+func envSet(key string) bool {
+	_, ok := os.LookupEnv("CLI_" + key)
+	return ok
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testFile = `
+network: testnet
+loglevel: INFO
+networks:
+  testnet:
+    manager_addr: http://testnet:8080
+    node_rpc_addr: http://testnet:8545
+    contract_address: "0x01"
+  local:
+    manager_addr: http://127.0.0.1:8080
+    node_rpc_addr: http://127.0.0.1:8545
+    contract_address: "0x02"
+    min_vdc_balance: 1
+`
+
+func TestFileApply(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yaml")
+	err = ioutil.WriteFile(path, []byte(testFile), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := LoadFile(path, true)
+	if err != nil {
+		t.Fatalf("LoadFile failed with err: %s", err)
+	}
+
+	os.Setenv("CLI_NODERPCADDR", "http://env:8545")
+	defer os.Unsetenv("CLI_NODERPCADDR")
+
+	tables := []struct {
+		network         string
+		managerAddr     string
+		nodeRPCAddr     string
+		contractAddress string
+		minVDCBalance   int
+		result          bool
+	}{
+		{"", "http://testnet:8080", "http://env:8545", "0x01", 15, true},
+		{"local", "http://127.0.0.1:8080", "http://env:8545", "0x02", 1, true},
+		{"staging", "", "", "", 15, false},
+	}
+
+	for i, table := range tables {
+		c := &Config{NodeRPCAddr: "http://env:8545", MinVDCBalance: 15, Loglevel: "FATAL"}
+
+		err := f.Apply(c, table.network)
+		if err != nil {
+			if table.result == true {
+				t.Errorf("Test %d Apply failed with err: %s", i, err)
+			}
+			continue
+		}
+
+		if c.ManagerAddr != table.managerAddr ||
+			c.NodeRPCAddr != table.nodeRPCAddr ||
+			c.ContractAddress != table.contractAddress ||
+			c.MinVDCBalance != table.minVDCBalance ||
+			c.Loglevel != "INFO" {
+			t.Errorf("Test %d Apply is incorrect, got: %+v", i, c)
+		}
+	}
+}
+
+func TestLoadFileMissing(t *testing.T) {
+	_, err := LoadFile("/nonexistent/config.yaml", false)
+	if err != nil {
+		t.Errorf("LoadFile of missing default file failed with err: %s", err)
+	}
+
+	_, err = LoadFile("/nonexistent/config.yaml", true)
+	if err == nil {
+		t.Errorf("LoadFile of missing explicit file succeeded")
+	}
+}
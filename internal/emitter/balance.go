@@ -0,0 +1,50 @@
+package emitter
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/VideoCoin/go-videocoin/common"
+	"github.com/VideoCoin/go-videocoin/ethclient"
+)
+
+// StreamDeposit is the value in wei locked by CreateStream.
+var StreamDeposit = new(big.Int).Exp(big.NewInt(10), big.NewInt(19), nil)
+
+type Balance struct {
+	Wei *big.Int
+	VDC *big.Float
+}
+
+func NewBalance(wei *big.Int) (*Balance, error) {
+	vdc, err := convertWeiToVDC(wei)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Balance{Wei: wei, VDC: vdc}, nil
+}
+
+func GetBalance(nodeRPCAddr string, address common.Address) (*Balance, error) {
+	client, err := ethclient.Dial(nodeRPCAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial eth client: %s", err.Error())
+	}
+	defer client.Close()
+
+	wei, err := client.BalanceAt(context.Background(), address, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBalance(wei)
+}
+
+func (b *Balance) CoversDeposit() bool {
+	return b.Wei.Cmp(StreamDeposit) >= 0
+}
+
+func (b *Balance) MeetsMinimum(vdc int) bool {
+	return b.VDC.Cmp(new(big.Float).SetInt64(int64(vdc))) >= 0
+}
@@ -0,0 +1,41 @@
+package emitter
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestBalance(t *testing.T) {
+	tables := []struct {
+		wei     string
+		minimum int
+		meets   bool
+		covers  bool
+	}{
+		{"0", 15, false, false},
+		{"10000000000000000000", 15, false, true},
+		{"15000000000000000000", 15, true, true},
+		{"9999999999999999999", 0, true, false},
+	}
+
+	for i, table := range tables {
+		wei, ok := new(big.Int).SetString(table.wei, 10)
+		if !ok {
+			t.Fatalf("Test %d failed to parse wei %s", i, table.wei)
+		}
+
+		b, err := NewBalance(wei)
+		if err != nil {
+			t.Errorf("Test %d NewBalance failed with err: %s", i, err)
+			continue
+		}
+
+		if b.MeetsMinimum(table.minimum) != table.meets {
+			t.Errorf("Test %d MeetsMinimum of %s wei is incorrect, want: %t", i, table.wei, table.meets)
+		}
+
+		if b.CoversDeposit() != table.covers {
+			t.Errorf("Test %d CoversDeposit of %s wei is incorrect, want: %t", i, table.wei, table.covers)
+		}
+	}
+}
@@ -72,24 +72,29 @@ func NewEmitterManager(c EmitterManagerConfig) (*emitterManager, error) {
 	}, nil
 }
 
-func (s *emitterManager) RequestStream() (*big.Int, error) {
+// RequestStream requests a new stream, its request and approve events are
+// logged in the background until ctx is done.
+func (s *emitterManager) RequestStream(ctx context.Context) (*big.Int, string, error) {
 	streamID := big.NewInt(int64(rand.Intn(math.MaxInt64)))
 
-	_, err := s.smManager.RequestStream(
+	tx, err := s.smManager.RequestStream(
 		s.transactOpts,
 		streamID,
 		"videocoin",
 		[]*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(2)},
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to request stream: %s", err.Error())
+		return nil, "", fmt.Errorf("failed to request stream: %s", err.Error())
 	}
 
 	go func() {
-		resultCh, errCh := s.eventListener.LogStreamRequestEvent(streamID, s.key.Address)
+		resultCh, errCh := s.eventListener.LogStreamRequestEvent(ctx, streamID, s.key.Address)
 
 		select {
 		case err := <-errCh:
+			if ctx.Err() != nil {
+				return
+			}
 			s.logger.WithError(err).Errorf("failed to watch stream requested")
 		case e := <-resultCh:
 			s.logger.Infof("received an event:%s\n", e.String())
@@ -97,42 +102,48 @@ func (s *emitterManager) RequestStream() (*big.Int, error) {
 	}()
 
 	go func() {
-		resultCh, errCh := s.eventListener.LogStreamApproveEvent(streamID)
+		resultCh, errCh := s.eventListener.LogStreamApproveEvent(ctx, streamID)
 
 		select {
 		case err := <-errCh:
+			if ctx.Err() != nil {
+				return
+			}
 			s.logger.WithError(err).Errorf("failed to watch stream approved")
 		case e := <-resultCh:
 			s.logger.Infof("received an event:%s\n", e.String())
 		}
 	}()
 
-	return streamID, nil
+	return streamID, tx.Hash().Hex(), nil
 }
 
-func (s *emitterManager) CreateStream(streamID *big.Int) (string, error) {
-	var i, e = big.NewInt(10), big.NewInt(19)
-	s.transactOpts.Value = i.Exp(i, e, nil)
+func (s *emitterManager) CreateStream(ctx context.Context, streamID *big.Int) (string, string, error) {
+	s.transactOpts.Value = new(big.Int).Set(StreamDeposit)
 	s.transactOpts.From = s.key.Address
 
-	_, err := s.smManager.CreateStream(
+	tx, err := s.smManager.CreateStream(
 		s.transactOpts,
 		streamID,
 	)
 	if err != nil {
-		return "", fmt.Errorf("failed to create stream: %s", err.Error())
+		return "", "", fmt.Errorf("failed to create stream: %s", err.Error())
 	}
 
-	resultCh, errCh := s.eventListener.LogStreamCreateEvent(streamID)
+	resultCh, errCh := s.eventListener.LogStreamCreateEvent(ctx, streamID)
 
+	// the transaction was sent, its hash is returned also when the event
+	// was not seen, the deposit may be held
 	select {
+	case <-ctx.Done():
+		return "", tx.Hash().Hex(), fmt.Errorf("failed to watch stream created: %s", ctx.Err().Error())
 	case err := <-errCh:
-		return "", fmt.Errorf("failed to watch stream created: %s", err.Error())
+		return "", tx.Hash().Hex(), fmt.Errorf("failed to watch stream created: %s", err.Error())
 	case <-time.After(30 * time.Second):
-		return "", fmt.Errorf("failed to watch stream created: timeout")
+		return "", tx.Hash().Hex(), fmt.Errorf("failed to watch stream created: timeout")
 	case e := <-resultCh:
 		s.logger.Infof("received an event:%s\n", e.String())
-		return e.StreamAddress.Hex(), nil
+		return e.StreamAddress.Hex(), tx.Hash().Hex(), nil
 	}
 }
 
@@ -0,0 +1,88 @@
+package key
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/VideoCoin/go-videocoin/accounts"
+	"github.com/VideoCoin/go-videocoin/accounts/keystore"
+	"github.com/VideoCoin/go-videocoin/crypto"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// DefaultDerivationPath is the BIP-44 path of the first account.
+const DefaultDerivationPath = "m/44'/60'/0'/0/0"
+
+func (k *keyStore) ImportPrivateKeyFile(filepath, password, privateKeyFile string) (*keystore.Key, error) {
+	b, err := ioutil.ReadFile(privateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key file: %s", err.Error())
+	}
+
+	privateKey, err := parsePrivateKey(string(b))
+	if err != nil {
+		return nil, err
+	}
+
+	return k.storeKey(filepath, password, privateKey)
+}
+
+func (k *keyStore) ImportMnemonic(filepath, password, mnemonic, derivationPath string) (*keystore.Key, error) {
+	privateKey, err := deriveMnemonicKey(mnemonic, derivationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return k.storeKey(filepath, password, privateKey)
+}
+
+func parsePrivateKey(s string) (*ecdsa.PrivateKey, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+
+	privateKey, err := crypto.HexToECDSA(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %s", err.Error())
+	}
+
+	return privateKey, nil
+}
+
+func deriveMnemonicKey(mnemonic, derivationPath string) (*ecdsa.PrivateKey, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mnemonic: %s", err.Error())
+	}
+
+	path, err := accounts.ParseDerivationPath(derivationPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse derivation path: %s", err.Error())
+	}
+
+	extendedKey, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create master key: %s", err.Error())
+	}
+
+	// Child drops leading zeros of private keys and derives other keys
+	// than BIP-32 and wallets for some mnemonics, Derive does not
+	for _, n := range path {
+		extendedKey, err = extendedKey.Derive(n)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive key: %s", err.Error())
+		}
+	}
+
+	privateKey, err := extendedKey.ECPrivKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive private key: %s", err.Error())
+	}
+
+	return privateKey.ToECDSA(), nil
+}
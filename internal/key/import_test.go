@@ -0,0 +1,67 @@
+package key
+
+import (
+	"testing"
+
+	"github.com/VideoCoin/go-videocoin/crypto"
+)
+
+func TestParsePrivateKey(t *testing.T) {
+	tables := []struct {
+		privateKey string
+		address    string
+		result     bool
+	}{
+		{"4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318", "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23", true},
+		{"0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318\n", "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23", true},
+		{"4c0883a6910293", "", false},
+		{"", "", false},
+	}
+
+	for i, table := range tables {
+		privateKey, err := parsePrivateKey(table.privateKey)
+		if err != nil {
+			if table.result == true {
+				t.Errorf("Test %d parsePrivateKey failed with err: %s", i, err)
+			}
+			continue
+		}
+
+		address := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+		if address != table.address {
+			t.Errorf("Test %d parsePrivateKey is incorrect, got: %s, want: %s", i, address, table.address)
+		}
+	}
+}
+
+func TestDeriveMnemonicKey(t *testing.T) {
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+	tables := []struct {
+		mnemonic string
+		path     string
+		address  string
+		result   bool
+	}{
+		{mnemonic, DefaultDerivationPath, "0x9858EfFD232B4033E47d90003D41EC34EcaEda94", true},
+		// the m/44' private key starts with a zero byte
+		{"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon cool", DefaultDerivationPath, "0xB171A07c646eE02C8E2130728971Ffa04eE73118", true},
+		{mnemonic, "m/not/a/path", "", false},
+		{"abandon abandon abandon", DefaultDerivationPath, "", false},
+	}
+
+	for i, table := range tables {
+		privateKey, err := deriveMnemonicKey(table.mnemonic, table.path)
+		if err != nil {
+			if table.result == true {
+				t.Errorf("Test %d deriveMnemonicKey failed with err: %s", i, err)
+			}
+			continue
+		}
+
+		address := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+		if address != table.address {
+			t.Errorf("Test %d deriveMnemonicKey is incorrect, got: %s, want: %s", i, address, table.address)
+		}
+	}
+}
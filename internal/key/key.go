@@ -1,10 +1,14 @@
 package key
 
 import (
+	"crypto/ecdsa"
 	"fmt"
+	"os"
 
 	"github.com/VideoCoin/common/bcops"
 	"github.com/VideoCoin/go-videocoin/accounts/keystore"
+	"github.com/VideoCoin/go-videocoin/crypto"
+	"github.com/pborman/uuid"
 )
 
 type keyStore struct {
@@ -28,3 +32,48 @@ func (k *keyStore) ImportKey(filepath, password string) (*keystore.Key, error) {
 func (k *keyStore) GetKey() *keystore.Key {
 	return k.key
 }
+
+func (k *keyStore) NewKey(filepath, password string) (*keystore.Key, error) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate private key: %s", err.Error())
+	}
+
+	return k.storeKey(filepath, password, privateKey)
+}
+
+// storeKey encrypts the private key into a v3 keystore file, an existing
+// file is never overwritten.
+func (k *keyStore) storeKey(filepath, password string, privateKey *ecdsa.PrivateKey) (*keystore.Key, error) {
+	key := &keystore.Key{
+		Id:         uuid.NewRandom(),
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		PrivateKey: privateKey,
+	}
+
+	keyJSON, err := keystore.EncryptKey(key, password, keystore.StandardScryptN, keystore.StandardScryptP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt private key: %s", err.Error())
+	}
+
+	f, err := os.OpenFile(filepath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create account file: %s", err.Error())
+	}
+
+	_, err = f.Write(keyJSON)
+	if err != nil {
+		f.Close()
+		os.Remove(filepath)
+		return nil, fmt.Errorf("failed to write account file: %s", err.Error())
+	}
+
+	err = f.Close()
+	if err != nil {
+		os.Remove(filepath)
+		return nil, fmt.Errorf("failed to write account file: %s", err.Error())
+	}
+
+	k.key = key
+	return key, nil
+}
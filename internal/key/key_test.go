@@ -2,6 +2,7 @@ package key
 
 import (
 	"io/ioutil"
+	"os"
 	"testing"
 )
 
@@ -81,3 +82,38 @@ func TestGetKey(t *testing.T) {
 		}
 	}
 }
+
+func TestNewKey(t *testing.T) {
+	filepath := "/tmp/newkey"
+	os.Remove(filepath)
+	defer os.Remove(filepath)
+
+	ks := new(keyStore)
+	key, err := ks.NewKey(filepath, testKeyPwd)
+	if err != nil {
+		t.Fatalf("NewKey failed with err: %s", err)
+	}
+
+	info, err := os.Stat(filepath)
+	if err != nil {
+		t.Fatalf("NewKey failed with err: %s", err)
+	}
+
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("NewKey file mode is incorrect, got: %s, want: %s", info.Mode().Perm(), os.FileMode(0600))
+	}
+
+	imported, err := ks.ImportKey(filepath, testKeyPwd)
+	if err != nil {
+		t.Fatalf("ImportKey of new key failed with err: %s", err)
+	}
+
+	if imported.Address != key.Address {
+		t.Errorf("ImportKey of new key is incorrect, got: %s, want: %s", imported.Address.Hex(), key.Address.Hex())
+	}
+
+	_, err = ks.NewKey(filepath, testKeyPwd)
+	if err == nil {
+		t.Errorf("NewKey overwrote an existing file")
+	}
+}
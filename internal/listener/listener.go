@@ -1,10 +1,12 @@
 package listener
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"time"
 
+	"github.com/VideoCoin/cli/internal/metrics"
 	sm "github.com/VideoCoin/common/streamManager"
 	"github.com/VideoCoin/go-videocoin/accounts/abi/bind"
 	"github.com/VideoCoin/go-videocoin/common"
@@ -31,7 +33,7 @@ func NewEventListener(c *EventListenerConfig) *EventListener {
 	}
 }
 
-func (e *EventListener) LogStreamRequestEvent(streamID *big.Int, address common.Address) (chan *event, chan error) {
+func (e *EventListener) LogStreamRequestEvent(ctx context.Context, streamID *big.Int, address common.Address) (chan *event, chan error) {
 	addresses := []common.Address{address}
 	streamIDs := []*big.Int{streamID}
 
@@ -41,19 +43,27 @@ func (e *EventListener) LogStreamRequestEvent(streamID *big.Int, address common.
 	go func() {
 		for timeout := time.After(e.timeout * time.Second); ; {
 			select {
+			case <-ctx.Done():
+				errCh <- ctx.Err()
+				return
 			case <-timeout:
+				metrics.ListenerErrors.WithLabelValues(EventStreamRequested).Inc()
 				errCh <- fmt.Errorf("failed to log stream request event and exit on timeout")
-				break
+				return
 			default:
 				iterator, err := e.smartContractManager.FilterStreamRequested(
-					new(bind.FilterOpts), addresses, streamIDs)
+					&bind.FilterOpts{Context: ctx}, addresses, streamIDs)
 				if err != nil {
+					metrics.ListenerErrors.WithLabelValues(EventStreamRequested).Inc()
 					errCh <- fmt.Errorf("failed to log stream request event: %s", err.Error())
+					return
 				}
 
 				for {
 					if iterator.Error() != nil {
-						errCh <- fmt.Errorf("failed to retrieve or parse log: %s", err.Error())
+						metrics.ListenerErrors.WithLabelValues(EventStreamRequested).Inc()
+						errCh <- fmt.Errorf("failed to retrieve or parse log: %s", iterator.Error().Error())
+						return
 					}
 					if iterator.Event != nil {
 						e := iterator.Event
@@ -74,7 +84,10 @@ func (e *EventListener) LogStreamRequestEvent(streamID *big.Int, address common.
 					}
 				}
 
-				time.Sleep(5 * time.Second)
+				select {
+				case <-ctx.Done():
+				case <-time.After(5 * time.Second):
+				}
 			}
 		}
 	}()
@@ -82,7 +95,7 @@ func (e *EventListener) LogStreamRequestEvent(streamID *big.Int, address common.
 	return resultCh, errCh
 }
 
-func (e *EventListener) LogStreamCreateEvent(streamID *big.Int) (chan *event, chan error) {
+func (e *EventListener) LogStreamCreateEvent(ctx context.Context, streamID *big.Int) (chan *event, chan error) {
 	streamAddresses := []common.Address{}
 	streamIDs := []*big.Int{streamID}
 
@@ -92,19 +105,27 @@ func (e *EventListener) LogStreamCreateEvent(streamID *big.Int) (chan *event, ch
 	go func() {
 		for timeout := time.After(e.timeout * time.Second); ; {
 			select {
+			case <-ctx.Done():
+				errCh <- ctx.Err()
+				return
 			case <-timeout:
+				metrics.ListenerErrors.WithLabelValues(EventStreamCreated).Inc()
 				errCh <- fmt.Errorf("failed to log stream created event and exit on timeout")
-				break
+				return
 			default:
 				iterator, err := e.smartContractManager.FilterStreamCreated(
-					new(bind.FilterOpts), streamAddresses, streamIDs)
+					&bind.FilterOpts{Context: ctx}, streamAddresses, streamIDs)
 				if err != nil {
+					metrics.ListenerErrors.WithLabelValues(EventStreamCreated).Inc()
 					errCh <- fmt.Errorf("failed to log stream created event: %s", err.Error())
+					return
 				}
 
 				for {
 					if iterator.Error() != nil {
-						errCh <- fmt.Errorf("failed to retrieve or parse log: %s", err.Error())
+						metrics.ListenerErrors.WithLabelValues(EventStreamCreated).Inc()
+						errCh <- fmt.Errorf("failed to retrieve or parse log: %s", iterator.Error().Error())
+						return
 					}
 					if iterator.Event != nil {
 						e := iterator.Event
@@ -126,7 +147,10 @@ func (e *EventListener) LogStreamCreateEvent(streamID *big.Int) (chan *event, ch
 					}
 				}
 
-				time.Sleep(5 * time.Second)
+				select {
+				case <-ctx.Done():
+				case <-time.After(5 * time.Second):
+				}
 			}
 		}
 	}()
@@ -134,7 +158,7 @@ func (e *EventListener) LogStreamCreateEvent(streamID *big.Int) (chan *event, ch
 	return resultCh, errCh
 }
 
-func (e *EventListener) LogStreamApproveEvent(streamID *big.Int) (chan *event, chan error) {
+func (e *EventListener) LogStreamApproveEvent(ctx context.Context, streamID *big.Int) (chan *event, chan error) {
 	streamIDs := []*big.Int{streamID}
 
 	resultCh := make(chan *event, 1)
@@ -143,19 +167,27 @@ func (e *EventListener) LogStreamApproveEvent(streamID *big.Int) (chan *event, c
 	go func() {
 		for timeout := time.After(e.timeout * time.Second); ; {
 			select {
+			case <-ctx.Done():
+				errCh <- ctx.Err()
+				return
 			case <-timeout:
+				metrics.ListenerErrors.WithLabelValues(EventStreamApproved).Inc()
 				errCh <- fmt.Errorf("failed to log stream approved event and exit on timeout")
-				break
+				return
 			default:
 				iterator, err := e.smartContractManager.FilterStreamApproved(
-					new(bind.FilterOpts), streamIDs)
+					&bind.FilterOpts{Context: ctx}, streamIDs)
 				if err != nil {
+					metrics.ListenerErrors.WithLabelValues(EventStreamApproved).Inc()
 					errCh <- fmt.Errorf("failed to log stream approved event: %s", err.Error())
+					return
 				}
 
 				for {
 					if iterator.Error() != nil {
-						errCh <- fmt.Errorf("failed to retrieve or parse log: %s", err.Error())
+						metrics.ListenerErrors.WithLabelValues(EventStreamApproved).Inc()
+						errCh <- fmt.Errorf("failed to retrieve or parse log: %s", iterator.Error().Error())
+						return
 					}
 					if iterator.Event != nil {
 						e := iterator.Event
@@ -176,7 +208,10 @@ func (e *EventListener) LogStreamApproveEvent(streamID *big.Int) (chan *event, c
 					}
 				}
 
-				time.Sleep(5 * time.Second)
+				select {
+				case <-ctx.Done():
+				case <-time.After(5 * time.Second):
+				}
 			}
 		}
 	}()
@@ -184,7 +219,7 @@ func (e *EventListener) LogStreamApproveEvent(streamID *big.Int) (chan *event, c
 	return resultCh, errCh
 }
 
-func (e *EventListener) LogInputChunkAddEvent(streamID *big.Int, chunkID *big.Int) (chan *event, chan error) {
+func (e *EventListener) LogInputChunkAddEvent(ctx context.Context, streamID *big.Int, chunkID *big.Int) (chan *event, chan error) {
 	streamIDs := []*big.Int{streamID}
 	chunkIDs := []*big.Int{chunkID}
 
@@ -194,19 +229,27 @@ func (e *EventListener) LogInputChunkAddEvent(streamID *big.Int, chunkID *big.In
 	go func() {
 		for timeout := time.After(e.timeout * time.Second); ; {
 			select {
+			case <-ctx.Done():
+				errCh <- ctx.Err()
+				return
 			case <-timeout:
+				metrics.ListenerErrors.WithLabelValues(EventStreamInputChunkAdded).Inc()
 				errCh <- fmt.Errorf("failed to log input chunk added event and exit on timeout")
-				break
+				return
 			default:
 				iterator, err := e.smartContractManager.FilterInputChunkAdded(
-					new(bind.FilterOpts), streamIDs, chunkIDs)
+					&bind.FilterOpts{Context: ctx}, streamIDs, chunkIDs)
 				if err != nil {
+					metrics.ListenerErrors.WithLabelValues(EventStreamInputChunkAdded).Inc()
 					errCh <- fmt.Errorf("failed to log input chunk added event: %s", err.Error())
+					return
 				}
 
 				for {
 					if iterator.Error() != nil {
-						errCh <- fmt.Errorf("failed to retrieve or parse log: %s", err.Error())
+						metrics.ListenerErrors.WithLabelValues(EventStreamInputChunkAdded).Inc()
+						errCh <- fmt.Errorf("failed to retrieve or parse log: %s", iterator.Error().Error())
+						return
 					}
 					if iterator.Event != nil {
 						e := iterator.Event
@@ -228,7 +271,10 @@ func (e *EventListener) LogInputChunkAddEvent(streamID *big.Int, chunkID *big.In
 					}
 				}
 
-				time.Sleep(5 * time.Second)
+				select {
+				case <-ctx.Done():
+				case <-time.After(5 * time.Second):
+				}
 			}
 		}
 	}()
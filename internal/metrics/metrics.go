@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+const namespace = "videocoin_cli"
+
+var (
+	JobStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "job_status",
+			Help:      "Last observed job status, the current status is set to 1.",
+		},
+		[]string{"status"},
+	)
+
+	AccountBalance = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "account_balance_vdc",
+			Help:      "Account balance in VDC.",
+		},
+	)
+
+	ListenerErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "listener_errors_total",
+			Help:      "Errors while polling blockchain events.",
+		},
+		[]string{"event"},
+	)
+
+	CloudRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "cloud_request_duration_seconds",
+			Help:      "Latency of cloud manager API requests.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"endpoint", "code"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(JobStatus, AccountBalance, ListenerErrors, CloudRequestDuration)
+}
+
+func SetJobStatus(status string) {
+	JobStatus.Reset()
+	JobStatus.WithLabelValues(status).Set(1)
+}
+
+// ObserveCloudRequest records the latency of a request started at started,
+// code is zero when the request failed without a response.
+func ObserveCloudRequest(endpoint string, code int, started time.Time) {
+	CloudRequestDuration.WithLabelValues(endpoint, strconv.Itoa(code)).Observe(time.Since(started).Seconds())
+}
+
+// Serve exposes metrics on addr until the process exits, it returns the
+// error when addr can not be listened on.
+func Serve(addr string, logger *logrus.Entry) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on metrics address: %s", err.Error())
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	go func() {
+		err := http.Serve(ln, mux)
+		if err != nil {
+			logger.WithError(err).Error("failed to serve metrics")
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/VideoCoin/cli/internal/transmitter"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	streamBytesDesc = prometheus.NewDesc(
+		namespace+"_transmitter_bytes_total", "Bytes read from the source per stream.",
+		[]string{"stream", "codec"}, nil)
+	streamPacketsDesc = prometheus.NewDesc(
+		namespace+"_transmitter_packets_total", "Packets read from the source per stream.",
+		[]string{"stream", "codec"}, nil)
+	bitrateDesc = prometheus.NewDesc(
+		namespace+"_transmitter_bitrate_bits_per_second", "Current source bitrate.",
+		nil, nil)
+	fpsDesc = prometheus.NewDesc(
+		namespace+"_transmitter_fps", "Current source frame rate.",
+		nil, nil)
+	keyFramesDesc = prometheus.NewDesc(
+		namespace+"_transmitter_keyframes_total", "Keyframes read from the source.",
+		nil, nil)
+	activeSourceDesc = prometheus.NewDesc(
+		namespace+"_transmitter_active_source", "Index of the active source, 0 is the primary source.",
+		nil, nil)
+	sourceReconnectsDesc = prometheus.NewDesc(
+		namespace+"_transmitter_source_reconnects_total", "Source reconnects.",
+		nil, nil)
+	destinationBytesDesc = prometheus.NewDesc(
+		namespace+"_transmitter_destination_bytes_total", "Bytes written per destination.",
+		[]string{"destination"}, nil)
+	destinationPacketsDesc = prometheus.NewDesc(
+		namespace+"_transmitter_destination_packets_total", "Packets written per destination.",
+		[]string{"destination"}, nil)
+	destinationDroppedDesc = prometheus.NewDesc(
+		namespace+"_transmitter_destination_dropped_total", "Packets dropped per destination.",
+		[]string{"destination"}, nil)
+	destinationDroppedGOPsDesc = prometheus.NewDesc(
+		namespace+"_transmitter_destination_dropped_gops_total", "Queue overflows per destination, each drops a gop.",
+		[]string{"destination"}, nil)
+	destinationReconnectsDesc = prometheus.NewDesc(
+		namespace+"_transmitter_destination_reconnects_total", "Reconnects per destination.",
+		[]string{"destination"}, nil)
+	destinationLatencyDesc = prometheus.NewDesc(
+		namespace+"_transmitter_destination_write_latency_seconds", "Moving average of packet write latency per destination.",
+		[]string{"destination"}, nil)
+)
+
+// transmitterCollector reads a stats snapshot on every scrape.
+type transmitterCollector struct {
+	stats func() transmitter.Stats
+}
+
+func RegisterTransmitter(stats func() transmitter.Stats) error {
+	return prometheus.Register(&transmitterCollector{stats: stats})
+}
+
+func (c *transmitterCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- streamBytesDesc
+	ch <- streamPacketsDesc
+	ch <- bitrateDesc
+	ch <- fpsDesc
+	ch <- keyFramesDesc
+	ch <- activeSourceDesc
+	ch <- sourceReconnectsDesc
+	ch <- destinationBytesDesc
+	ch <- destinationPacketsDesc
+	ch <- destinationDroppedDesc
+	ch <- destinationDroppedGOPsDesc
+	ch <- destinationReconnectsDesc
+	ch <- destinationLatencyDesc
+}
+
+func (c *transmitterCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+
+	for _, s := range stats.Streams {
+		idx := strconv.Itoa(s.Idx)
+		ch <- prometheus.MustNewConstMetric(streamBytesDesc, prometheus.CounterValue, float64(s.Bytes), idx, s.Codec)
+		ch <- prometheus.MustNewConstMetric(streamPacketsDesc, prometheus.CounterValue, float64(s.Packets), idx, s.Codec)
+	}
+
+	ch <- prometheus.MustNewConstMetric(bitrateDesc, prometheus.GaugeValue, stats.Bitrate)
+	ch <- prometheus.MustNewConstMetric(fpsDesc, prometheus.GaugeValue, stats.FPS)
+	ch <- prometheus.MustNewConstMetric(keyFramesDesc, prometheus.CounterValue, float64(stats.KeyFrames))
+	ch <- prometheus.MustNewConstMetric(activeSourceDesc, prometheus.GaugeValue, float64(stats.ActiveSource))
+	ch <- prometheus.MustNewConstMetric(sourceReconnectsDesc, prometheus.CounterValue, float64(stats.SourceReconnects))
+
+	simulcast := 0
+	for _, d := range stats.Destinations {
+		// urls carry stream keys, so destinations are labeled by role
+		name := "primary"
+		switch {
+		case d.Local:
+			name = "record"
+		case !d.Primary:
+			simulcast++
+			name = "simulcast_" + strconv.Itoa(simulcast)
+		}
+
+		ch <- prometheus.MustNewConstMetric(destinationBytesDesc, prometheus.CounterValue, float64(d.Bytes), name)
+		ch <- prometheus.MustNewConstMetric(destinationPacketsDesc, prometheus.CounterValue, float64(d.Packets), name)
+		ch <- prometheus.MustNewConstMetric(destinationDroppedDesc, prometheus.CounterValue, float64(d.Dropped), name)
+		ch <- prometheus.MustNewConstMetric(destinationDroppedGOPsDesc, prometheus.CounterValue, float64(d.DroppedGOPs), name)
+		ch <- prometheus.MustNewConstMetric(destinationReconnectsDesc, prometheus.CounterValue, float64(d.Reconnects), name)
+		ch <- prometheus.MustNewConstMetric(destinationLatencyDesc, prometheus.GaugeValue, d.WriteLatency.Seconds(), name)
+	}
+}
@@ -0,0 +1,197 @@
+package probe
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/VideoCoin/cli/internal/source"
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/codec/h264parser"
+	"github.com/nareix/joy4/format"
+)
+
+func init() {
+	format.RegisterAll()
+}
+
+// readTimeout bounds reading a sampling window from a live source that
+// stalls.
+const readTimeout = 10 * time.Second
+
+type StreamInfo struct {
+	Idx          int    `json:"idx"`
+	Type         string `json:"type"`
+	Codec        string `json:"codec"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+	PixelFormat  string `json:"pixel_format,omitempty"`
+	Profile      string `json:"profile,omitempty"`
+	ProfileIdc   uint8  `json:"profile_idc,omitempty"`
+	Level        uint8  `json:"level,omitempty"`
+	SampleRate   int    `json:"sample_rate,omitempty"`
+	Channels     int    `json:"channels,omitempty"`
+	SampleFormat string `json:"sample_format,omitempty"`
+
+	Packets uint64  `json:"packets"`
+	Bytes   uint64  `json:"bytes"`
+	Bitrate float64 `json:"bitrate"`
+	FPS     float64 `json:"fps,omitempty"`
+	// GOP is the average number of frames between keyframes and
+	// KeyFrameInterval the average time, both are zero when less than two
+	// keyframes were sampled.
+	GOP              float64       `json:"gop,omitempty"`
+	KeyFrameInterval time.Duration `json:"keyframe_interval,omitempty"`
+}
+
+func (s *StreamInfo) IsVideo() bool {
+	return s.Type == "video"
+}
+
+// LevelString formats the H.264 level as e.g. 4.1.
+func (s *StreamInfo) LevelString() string {
+	return fmt.Sprintf("%d.%d", s.Level/10, s.Level%10)
+}
+
+type Result struct {
+	Source  string       `json:"source"`
+	Streams []StreamInfo `json:"streams"`
+	// Duration is the span of sampled packet timestamps.
+	Duration time.Duration `json:"duration"`
+	Bitrate  float64       `json:"bitrate"`
+}
+
+// Video returns the first video stream or nil.
+func (r *Result) Video() *StreamInfo {
+	for i := range r.Streams {
+		if r.Streams[i].IsVideo() {
+			return &r.Streams[i]
+		}
+	}
+
+	return nil
+}
+
+// Audio returns the first audio stream or nil.
+func (r *Result) Audio() *StreamInfo {
+	for i := range r.Streams {
+		if !r.Streams[i].IsVideo() {
+			return &r.Streams[i]
+		}
+	}
+
+	return nil
+}
+
+// Probe opens the source, inspects its codec data and samples packets for
+// window of stream time.
+func Probe(uri string, window time.Duration) (*Result, error) {
+	conn, err := source.Open(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source connection: %s", err.Error())
+	}
+	defer conn.Close()
+
+	streams, err := conn.Streams()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire source connection streams: %s", err.Error())
+	}
+
+	var timedOut int32
+	timer := time.AfterFunc(window+readTimeout, func() {
+		atomic.StoreInt32(&timedOut, 1)
+		conn.Close()
+	})
+	defer timer.Stop()
+
+	s := newSampler(streams)
+
+	for !s.Done(window) {
+		pkt, err := conn.ReadPacket()
+		if err != nil {
+			if err == io.EOF || atomic.LoadInt32(&timedOut) == 1 {
+				break
+			}
+
+			return nil, fmt.Errorf("failed to read source packet: %s", err.Error())
+		}
+
+		s.Observe(pkt)
+	}
+
+	r := s.Result()
+	r.Source = source.Redact(uri)
+
+	return r, nil
+}
+
+func describeStream(idx int, stream av.CodecData) StreamInfo {
+	info := StreamInfo{
+		Idx:   idx,
+		Type:  "audio",
+		Codec: stream.Type().String(),
+	}
+
+	if stream.Type().IsVideo() {
+		info.Type = "video"
+	}
+
+	if video, ok := stream.(av.VideoCodecData); ok {
+		info.Width = video.Width()
+		info.Height = video.Height()
+	}
+
+	if h264, ok := stream.(h264parser.CodecData); ok {
+		info.ProfileIdc = h264.RecordInfo.AVCProfileIndication
+		info.Level = h264.RecordInfo.AVCLevelIndication
+		info.Profile = h264ProfileName(info.ProfileIdc)
+		info.PixelFormat = h264PixelFormat(info.ProfileIdc)
+	}
+
+	if audio, ok := stream.(av.AudioCodecData); ok {
+		info.SampleRate = audio.SampleRate()
+		info.Channels = audio.ChannelLayout().Count()
+		info.SampleFormat = audio.SampleFormat().String()
+	}
+
+	return info
+}
+
+func h264ProfileName(idc uint8) string {
+	switch idc {
+	case 66:
+		return "Baseline"
+	case 77:
+		return "Main"
+	case 88:
+		return "Extended"
+	case 100:
+		return "High"
+	case 110:
+		return "High 10"
+	case 122:
+		return "High 4:2:2"
+	case 244:
+		return "High 4:4:4"
+	default:
+		return fmt.Sprintf("unknown (%d)", idc)
+	}
+}
+
+// h264PixelFormat infers the pixel format from the profile, the chroma
+// format is not part of the parsed codec data.
+func h264PixelFormat(idc uint8) string {
+	switch idc {
+	case 66, 77, 88, 100:
+		return "yuv420p"
+	case 110:
+		return "yuv420p10"
+	case 122:
+		return "yuv422p"
+	case 244:
+		return "yuv444p"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,137 @@
+package probe
+
+import (
+	"fmt"
+	"time"
+)
+
+// Profile describes the sources a transcoding profile is tuned for. The
+// manager api only reports a profile id, the limits are the cli's own
+// copy of the transcoder settings and are checked as warnings. Only the
+// codecs are enforced, the flv ingest carries H264 video and AAC audio.
+type Profile struct {
+	ID   int64
+	Name string
+
+	MaxWidth  int
+	MaxHeight int
+	// H264Profiles lists the accepted profile_idc values, MaxLevel is the
+	// highest level_idc the transcoder is tuned for.
+	H264Profiles []uint8
+	MaxLevel     uint8
+	SampleRates  []int
+
+	MaxKeyFrameInterval time.Duration
+	MaxBitrate          float64
+}
+
+// Profiles are the transcoding profiles known to the cli by id.
+var Profiles = map[int64]Profile{
+	1: {
+		ID:   1,
+		Name: "H.264 1080p",
+		// Output resolution of the profile, larger sources are scaled
+		// down.
+		MaxWidth:  1920,
+		MaxHeight: 1080,
+		// Baseline, Main and High, the profiles every H.264 decoder
+		// supports, and level 4.2, the highest level for 1080p60.
+		H264Profiles: []uint8{66, 77, 100},
+		MaxLevel:     42,
+		// The rates the AAC encoder of the profile resamples without
+		// loss.
+		SampleRates: []int{44100, 48000},
+		// Segments are cut on keyframes, longer gops make longer
+		// segments and more latency.
+		MaxKeyFrameInterval: 4 * time.Second,
+		// Recommended ingest bitrate for 1080p.
+		MaxBitrate: 8e6,
+	},
+}
+
+type Issue struct {
+	// Fatal issues make the source unusable with the profile, others
+	// degrade the output.
+	Fatal   bool   `json:"fatal"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	return i.Message
+}
+
+// Check compares a probe result with the profile.
+func (p Profile) Check(r *Result) []Issue {
+	issues := []Issue{}
+	fatal := func(format string, args ...interface{}) {
+		issues = append(issues, Issue{Fatal: true, Message: fmt.Sprintf(format, args...)})
+	}
+	warn := func(format string, args ...interface{}) {
+		issues = append(issues, Issue{Message: fmt.Sprintf(format, args...)})
+	}
+
+	video := r.Video()
+	if video == nil {
+		fatal("source has no video stream")
+	} else {
+		if video.Codec != "H264" {
+			fatal("video codec %s is not supported, must be H264", video.Codec)
+		} else {
+			if !containsUint8(p.H264Profiles, video.ProfileIdc) {
+				warn("H.264 profile %s is not supported by profile %q", video.Profile, p.Name)
+			}
+			if video.Level > p.MaxLevel {
+				warn("H.264 level %s is above %d.%d", video.LevelString(), p.MaxLevel/10, p.MaxLevel%10)
+			}
+		}
+
+		if video.Width > p.MaxWidth || video.Height > p.MaxHeight {
+			warn("resolution %dx%d exceeds %dx%d", video.Width, video.Height, p.MaxWidth, p.MaxHeight)
+		}
+
+		switch {
+		case video.KeyFrameInterval == 0:
+			warn("keyframe interval could not be measured in %s", r.Duration)
+		case video.KeyFrameInterval > p.MaxKeyFrameInterval:
+			warn("keyframe interval %s is longer than %s", video.KeyFrameInterval, p.MaxKeyFrameInterval)
+		}
+	}
+
+	audio := r.Audio()
+	if audio == nil {
+		warn("source has no audio stream")
+	} else {
+		if audio.Codec != "AAC" {
+			fatal("audio codec %s is not supported, must be AAC", audio.Codec)
+		}
+		if !containsInt(p.SampleRates, audio.SampleRate) {
+			warn("audio sample rate %d is not supported by profile %q", audio.SampleRate, p.Name)
+		}
+	}
+
+	if r.Bitrate > p.MaxBitrate {
+		warn("bitrate %.0f kbps is above %.0f kbps", r.Bitrate/1e3, p.MaxBitrate/1e3)
+	}
+
+	return issues
+}
+
+func containsUint8(values []uint8, v uint8) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,72 @@
+package probe
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProfileCheck(t *testing.T) {
+	video := StreamInfo{
+		Type:             "video",
+		Codec:            "H264",
+		Width:            1280,
+		Height:           720,
+		ProfileIdc:       100,
+		Level:            31,
+		KeyFrameInterval: 2 * time.Second,
+	}
+	audio := StreamInfo{Type: "audio", Codec: "AAC", SampleRate: 44100}
+
+	tables := []struct {
+		name   string
+		result Result
+		fatal  bool
+		issues int
+	}{
+		{"compatible", Result{Streams: []StreamInfo{video, audio}, Bitrate: 3e6}, false, 0},
+		{"no audio", Result{Streams: []StreamInfo{video}}, false, 1},
+		{"no video", Result{Streams: []StreamInfo{audio}}, true, 1},
+		{"4k", Result{Streams: []StreamInfo{withSize(video, 3840, 2160), audio}}, false, 1},
+		{"high 4:4:4", Result{Streams: []StreamInfo{withProfileIdc(video, 244), audio}}, false, 1},
+		{"long gop", Result{Streams: []StreamInfo{withKeyFrameInterval(video, 10*time.Second), audio}}, false, 1},
+		{"bitrate", Result{Streams: []StreamInfo{video, audio}, Bitrate: 20e6}, false, 1},
+		{"sample rate", Result{Streams: []StreamInfo{video, {Type: "audio", Codec: "AAC", SampleRate: 8000}}}, false, 1},
+		{"hevc", Result{Streams: []StreamInfo{withCodec(video, "H265"), audio}}, true, 1},
+	}
+
+	for _, table := range tables {
+		issues := Profiles[1].Check(&table.result)
+		if len(issues) != table.issues {
+			t.Errorf("%s: got issues %v, want %d", table.name, issues, table.issues)
+			continue
+		}
+
+		fatal := false
+		for _, issue := range issues {
+			fatal = fatal || issue.Fatal
+		}
+		if fatal != table.fatal {
+			t.Errorf("%s: got fatal %t, want %t", table.name, fatal, table.fatal)
+		}
+	}
+}
+
+func withSize(s StreamInfo, width, height int) StreamInfo {
+	s.Width, s.Height = width, height
+	return s
+}
+
+func withProfileIdc(s StreamInfo, idc uint8) StreamInfo {
+	s.ProfileIdc = idc
+	return s
+}
+
+func withKeyFrameInterval(s StreamInfo, interval time.Duration) StreamInfo {
+	s.KeyFrameInterval = interval
+	return s
+}
+
+func withCodec(s StreamInfo, codec string) StreamInfo {
+	s.Codec = codec
+	return s
+}
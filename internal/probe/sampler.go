@@ -0,0 +1,125 @@
+package probe
+
+import (
+	"time"
+
+	"github.com/nareix/joy4/av"
+)
+
+type streamSample struct {
+	first, last time.Duration
+	started     bool
+
+	frames        int
+	keyFrames     int
+	firstKeyFrame time.Duration
+	lastKeyFrame  time.Duration
+	// frames read up to the first keyframe and between the first and the
+	// last keyframe
+	leadFrames int
+	gopFrames  int
+}
+
+// sampler measures bitrate, fps and gop length from packet timestamps,
+// so sampling a file is as accurate as sampling a live source.
+type sampler struct {
+	info    []StreamInfo
+	samples []streamSample
+
+	started     bool
+	first, last time.Duration
+}
+
+func newSampler(streams []av.CodecData) *sampler {
+	s := &sampler{
+		info:    make([]StreamInfo, len(streams)),
+		samples: make([]streamSample, len(streams)),
+	}
+
+	for i, stream := range streams {
+		s.info[i] = describeStream(i, stream)
+	}
+
+	return s
+}
+
+func (s *sampler) Observe(pkt av.Packet) {
+	if int(pkt.Idx) >= len(s.info) {
+		return
+	}
+
+	if !s.started {
+		s.first = pkt.Time
+		s.started = true
+	}
+	if pkt.Time > s.last {
+		s.last = pkt.Time
+	}
+
+	info := &s.info[pkt.Idx]
+	info.Packets++
+	info.Bytes += uint64(len(pkt.Data))
+
+	sample := &s.samples[pkt.Idx]
+	if !sample.started {
+		sample.first = pkt.Time
+		sample.started = true
+	}
+	if pkt.Time > sample.last {
+		sample.last = pkt.Time
+	}
+
+	if !info.IsVideo() {
+		return
+	}
+
+	sample.frames++
+	if pkt.IsKeyFrame {
+		if sample.keyFrames == 0 {
+			sample.firstKeyFrame = pkt.Time
+			sample.leadFrames = sample.frames
+		}
+		sample.keyFrames++
+		sample.lastKeyFrame = pkt.Time
+		sample.gopFrames = sample.frames - sample.leadFrames
+	}
+}
+
+// Done reports whether window of stream time was sampled.
+func (s *sampler) Done(window time.Duration) bool {
+	return s.started && s.last-s.first >= window
+}
+
+func (s *sampler) Result() *Result {
+	r := &Result{
+		Streams:  append([]StreamInfo(nil), s.info...),
+		Duration: s.last - s.first,
+	}
+
+	var bytes uint64
+	for i := range r.Streams {
+		info := &r.Streams[i]
+		sample := s.samples[i]
+		bytes += info.Bytes
+
+		if r.Duration > 0 {
+			info.Bitrate = float64(info.Bytes*8) / r.Duration.Seconds()
+		}
+
+		if span := sample.last - sample.first; info.IsVideo() && info.Packets > 1 && span > 0 {
+			info.FPS = float64(info.Packets-1) / span.Seconds()
+		}
+
+		if sample.keyFrames > 1 {
+			gops := sample.keyFrames - 1
+			info.GOP = float64(sample.gopFrames) / float64(gops)
+			info.KeyFrameInterval = (sample.lastKeyFrame - sample.firstKeyFrame) / time.Duration(gops)
+		}
+	}
+
+	if r.Duration > 0 {
+		r.Bitrate = float64(bytes*8) / r.Duration.Seconds()
+	}
+
+	return r
+}
@@ -0,0 +1,55 @@
+package probe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nareix/joy4/av"
+)
+
+type testCodecData struct {
+	codecType av.CodecType
+}
+
+func (c testCodecData) Type() av.CodecType {
+	return c.codecType
+}
+
+func TestSampler(t *testing.T) {
+	s := newSampler([]av.CodecData{testCodecData{av.H264}, testCodecData{av.AAC}})
+
+	// 25 fps video with 1000 byte frames joined mid gop, a keyframe every
+	// 50 frames, 500 byte audio packets every 40ms
+	for i := 10; i <= 160; i++ {
+		ts := time.Duration(i) * 40 * time.Millisecond
+		s.Observe(av.Packet{Idx: 0, IsKeyFrame: i%50 == 0, Time: ts, Data: make([]byte, 1000)})
+		s.Observe(av.Packet{Idx: 1, Time: ts, Data: make([]byte, 500)})
+
+		if i < 160 && s.Done(6*time.Second) {
+			t.Fatalf("sampler done after %s", ts)
+		}
+	}
+
+	if !s.Done(6 * time.Second) {
+		t.Fatalf("sampler not done after 6s")
+	}
+
+	r := s.Result()
+
+	if r.Duration != 6*time.Second {
+		t.Errorf("got duration %s, want 6s", r.Duration)
+	}
+
+	video := r.Video()
+	if video.Packets != 151 || video.FPS != 25 {
+		t.Errorf("got %d video packets at %f fps, want 151 at 25 fps", video.Packets, video.FPS)
+	}
+
+	if video.GOP != 50 || video.KeyFrameInterval != 2*time.Second {
+		t.Errorf("got gop %f and keyframe interval %s, want 50 and 2s", video.GOP, video.KeyFrameInterval)
+	}
+
+	if r.Bitrate != 151*1500*8/6 {
+		t.Errorf("got bitrate %f, want %d", r.Bitrate, 151*1500*8/6)
+	}
+}
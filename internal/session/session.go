@@ -0,0 +1,169 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Session is saved after every completed stage. Source and Backups are
+// saved without passwords, SourceRedacted is set when one was removed and
+// the sources must be passed again to resume.
+type Session struct {
+	StreamID        string            `json:"stream_id"`
+	ContractAddress string            `json:"contract_address,omitempty"`
+	JobStatus       string            `json:"job_status,omitempty"`
+	Source          string            `json:"source"`
+	Backups         []string          `json:"backups,omitempty"`
+	SourceRedacted  bool              `json:"source_redacted,omitempty"`
+	SourceFile      bool              `json:"source_file,omitempty"`
+	SourceListen    bool              `json:"source_listen,omitempty"`
+	Destination     string            `json:"destination,omitempty"`
+	OutputURL       string            `json:"output_url,omitempty"`
+	TxHashes        map[string]string `json:"tx_hashes,omitempty"`
+	CompletedStages []string          `json:"completed_stages"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+type Store struct {
+	dir string
+}
+
+// DefaultDir returns $XDG_STATE_HOME/videocoin-cli/sessions, falling back
+// to ~/.local/state when XDG_STATE_HOME is not set.
+func DefaultDir() (string, error) {
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %s", err.Error())
+		}
+		stateHome = filepath.Join(home, ".local", "state")
+	}
+
+	return filepath.Join(stateHome, "videocoin-cli", "sessions"), nil
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// path returns the session file of the stream, stream ids are decimal
+// numbers, anything else could point outside of the store.
+func (s *Store) path(streamID string) (string, error) {
+	id, ok := new(big.Int).SetString(streamID, 10)
+	if !ok || id.Sign() < 0 || id.String() != streamID {
+		return "", fmt.Errorf("invalid stream id: %q", streamID)
+	}
+
+	return filepath.Join(s.dir, streamID+".json"), nil
+}
+
+// Save writes the session atomically, so a crash never leaves a partially
+// written session behind.
+func (s *Store) Save(sess *Session) error {
+	if sess.StreamID == "" {
+		return fmt.Errorf("session stream id is not set")
+	}
+
+	path, err := s.path(sess.StreamID)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(s.dir, 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create state directory: %s", err.Error())
+	}
+
+	sess.UpdatedAt = time.Now().UTC()
+
+	b, err := json.MarshalIndent(sess, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(s.dir, sess.StreamID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create session file: %s", err.Error())
+	}
+
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write session file: %s", err.Error())
+	}
+
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write session file: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (s *Store) Load(streamID string) (*Session, error) {
+	path, err := s.path(streamID)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("session of stream %s was not found", streamID)
+		}
+		return nil, fmt.Errorf("failed to read session file: %s", err.Error())
+	}
+
+	sess := new(Session)
+	err = json.Unmarshal(b, sess)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse session file: %s", err.Error())
+	}
+
+	return sess, nil
+}
+
+func (s *Store) Remove(streamID string) error {
+	path, err := s.path(streamID)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (sess *Session) CompleteStage(name string) {
+	for _, stage := range sess.CompletedStages {
+		if stage == name {
+			return
+		}
+	}
+
+	sess.CompletedStages = append(sess.CompletedStages, name)
+}
+
+func (sess *Session) SetTxHash(name, hash string) {
+	if sess.TxHashes == nil {
+		sess.TxHashes = map[string]string{}
+	}
+
+	sess.TxHashes[name] = hash
+}
@@ -0,0 +1,77 @@
+package session
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewStore(dir)
+
+	sess := &Session{
+		StreamID: "42",
+		Source:   "rtmp://127.0.0.1:1936/stream",
+	}
+	sess.CompleteStage("request stream")
+	sess.CompleteStage("request stream")
+	sess.SetTxHash("request stream", "0x01")
+
+	err = store.Save(sess)
+	if err != nil {
+		t.Fatalf("Save failed with err: %s", err)
+	}
+
+	loaded, err := store.Load("42")
+	if err != nil {
+		t.Fatalf("Load failed with err: %s", err)
+	}
+
+	if loaded.Source != sess.Source || len(loaded.CompletedStages) != 1 || loaded.TxHashes["request stream"] != "0x01" {
+		t.Errorf("Load is incorrect, got: %+v, want: %+v", loaded, sess)
+	}
+
+	err = store.Remove("42")
+	if err != nil {
+		t.Fatalf("Remove failed with err: %s", err)
+	}
+
+	_, err = store.Load("42")
+	if err == nil {
+		t.Errorf("Load of removed session succeeded")
+	}
+
+	err = store.Save(&Session{})
+	if err == nil {
+		t.Errorf("Save of session without stream id succeeded")
+	}
+}
+
+func TestStoreInvalidStreamID(t *testing.T) {
+	store := NewStore("sessions")
+
+	tables := []string{
+		"",
+		"../42",
+		"42/../../etc/passwd",
+		"-42",
+		"+42",
+		"042",
+		"0x2a",
+	}
+
+	for i, streamID := range tables {
+		if _, err := store.Load(streamID); err == nil {
+			t.Errorf("Test %d Load of stream id %q succeeded", i, streamID)
+		}
+		if err := store.Remove(streamID); err == nil {
+			t.Errorf("Test %d Remove of stream id %q succeeded", i, streamID)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package source
+
+import (
+	"fmt"
+
+	"github.com/nareix/joy4/av"
+)
+
+// relayable are the codecs rtmp destinations accept.
+var relayable = map[av.CodecType]bool{
+	av.H264: true,
+	av.AAC:  true,
+}
+
+// filteredDemuxer hides the streams rtmp can not carry, e.g. the G.711
+// audio of ip cameras, so the video is still relayed.
+type filteredDemuxer struct {
+	av.DemuxCloser
+	mapping []int
+	streams []av.CodecData
+}
+
+func filterStreams(conn av.DemuxCloser) av.DemuxCloser {
+	return &filteredDemuxer{DemuxCloser: conn}
+}
+
+func (f *filteredDemuxer) Streams() ([]av.CodecData, error) {
+	if f.mapping != nil {
+		return f.streams, nil
+	}
+
+	streams, err := f.DemuxCloser.Streams()
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := make([]int, len(streams))
+	var filtered []av.CodecData
+
+	for i, stream := range streams {
+		mapping[i] = -1
+		if relayable[stream.Type()] {
+			mapping[i] = len(filtered)
+			filtered = append(filtered, stream)
+		}
+	}
+
+	if len(filtered) == 0 {
+		return nil, fmt.Errorf("source has no h264 or aac stream")
+	}
+
+	f.mapping = mapping
+	f.streams = filtered
+
+	return f.streams, nil
+}
+
+func (f *filteredDemuxer) ReadPacket() (av.Packet, error) {
+	if _, err := f.Streams(); err != nil {
+		return av.Packet{}, err
+	}
+
+	for {
+		pkt, err := f.DemuxCloser.ReadPacket()
+		if err != nil {
+			return pkt, err
+		}
+
+		if int(pkt.Idx) >= len(f.mapping) || f.mapping[pkt.Idx] < 0 {
+			continue
+		}
+
+		pkt.Idx = int8(f.mapping[pkt.Idx])
+
+		return pkt, nil
+	}
+}
@@ -0,0 +1,34 @@
+package source
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/nareix/joy4/av"
+)
+
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: streamTimeout}).DialContext,
+		TLSHandshakeTimeout:   streamTimeout,
+		ResponseHeaderTimeout: streamTimeout,
+	},
+}
+
+// openHTTP pulls an http-flv stream, the response body is demuxed until
+// the origin ends it.
+func openHTTP(uri string) (av.DemuxCloser, error) {
+	resp, err := httpClient.Get(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("origin responded with %s", resp.Status)
+	}
+
+	return demux(resp.Body)
+}
@@ -0,0 +1,33 @@
+package source
+
+import (
+	"time"
+
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/format/rtsp"
+)
+
+const (
+	rtspTimeout = 10 * time.Second
+	// rtspKeepAlive refreshes the session before cameras expire it, most
+	// default to 60 seconds.
+	rtspKeepAlive = 30 * time.Second
+)
+
+// openRTSP dials the camera, media is received interleaved on the rtsp tcp
+// connection so it passes NATs and firewalls. Basic and digest credentials
+// are taken from the url.
+func openRTSP(uri string) (av.DemuxCloser, error) {
+	client, err := rtsp.DialTimeout(uri, rtspTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	client.RtspTimeout = rtspTimeout
+	client.RtpTimeout = rtspTimeout
+	client.RtpKeepAliveTimeout = rtspKeepAlive
+	// cameras send the odd malformed rtp packet, it must not end the stream
+	client.SkipErrRtpBlock = true
+
+	return filterStreams(client), nil
+}
@@ -0,0 +1,104 @@
+// Package source opens the streams the cli relays, the url scheme selects
+// the protocol.
+package source
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/av/avutil"
+	"github.com/nareix/joy4/format"
+)
+
+func init() {
+	format.RegisterAll()
+}
+
+// Open connects to the source at uri. Schemes without a dedicated client,
+// rtmp and local files, are opened by joy4.
+func Open(uri string) (av.DemuxCloser, error) {
+	conn, err := open(uri)
+	if err != nil {
+		return nil, redactError(uri, err)
+	}
+
+	return conn, nil
+}
+
+func open(uri string) (av.DemuxCloser, error) {
+	if uri == Stdin {
+		return openStdin()
+	}
+
+	switch scheme(uri) {
+	case "rtsp":
+		return openRTSP(uri)
+	case "tcp":
+		return openTCP(uri)
+	case "udp":
+		return openUDP(uri)
+	case "http", "https":
+		return openHTTP(uri)
+	default:
+		return avutil.Open(uri)
+	}
+}
+
+// Once reports whether the source at uri can only be read once, stdin
+// and tcp servers like ffmpeg serve a single reader. Such sources can not
+// be probed before streaming.
+func Once(uri string) bool {
+	return uri == Stdin || scheme(uri) == "tcp"
+}
+
+// Redact hides the password of uri, source urls of cameras often carry
+// credentials.
+func Redact(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.User == nil {
+		return uri
+	}
+
+	if _, ok := u.User.Password(); !ok {
+		return uri
+	}
+	u.User = url.UserPassword(u.User.Username(), "xxxxx")
+
+	return u.String()
+}
+
+// redactError hides the password of uri in err, errors of url parsing and
+// dialing quote the url, also when it can not be parsed.
+func redactError(uri string, err error) error {
+	i := strings.Index(uri, "://")
+	if i < 0 {
+		return err
+	}
+
+	host := uri[i+3:]
+	if j := strings.IndexAny(host, "/?#"); j >= 0 {
+		host = host[:j]
+	}
+
+	at := strings.LastIndex(host, "@")
+	colon := strings.Index(host, ":")
+	if at < 0 || colon < 0 || colon > at {
+		return err
+	}
+
+	userinfo := host[:at+1]
+	redacted := host[:colon] + ":xxxxx@"
+
+	return errors.New(strings.Replace(err.Error(), userinfo, redacted, -1))
+}
+
+func scheme(uri string) string {
+	i := strings.Index(uri, "://")
+	if i < 0 {
+		return ""
+	}
+
+	return strings.ToLower(uri[:i])
+}
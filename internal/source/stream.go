@@ -0,0 +1,162 @@
+package source
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/format/flv"
+	"github.com/nareix/joy4/format/ts"
+)
+
+const (
+	// Stdin is the source url of a stream piped into the cli.
+	Stdin = "-"
+
+	// streamTimeout bounds connecting and reading the stream header, the
+	// transmitter can not be stopped while it waits.
+	streamTimeout = 10 * time.Second
+	// maxDatagramSize fits any udp payload.
+	maxDatagramSize = 64 * 1024
+	tsSyncByte      = 0x47
+)
+
+var stdinOpened int32
+
+// streamConn demuxes a byte stream, the demuxer is chosen by sniffing the
+// stream header.
+type streamConn struct {
+	av.Demuxer
+	rc io.ReadCloser
+}
+
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// demux reads mpegts or flv from rc, rc is closed on error.
+func demux(rc io.ReadCloser) (av.DemuxCloser, error) {
+	// regular files do not support deadlines and never block anyway
+	if d, ok := rc.(readDeadliner); ok {
+		d.SetReadDeadline(time.Now().Add(streamTimeout))
+	}
+
+	br := bufio.NewReaderSize(rc, maxDatagramSize)
+
+	header, err := br.Peek(3)
+	if err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("failed to read stream header: %s", err.Error())
+	}
+
+	c := &streamConn{rc: rc}
+
+	switch {
+	case string(header) == "FLV":
+		c.Demuxer = flv.NewDemuxer(br)
+	case header[0] == tsSyncByte:
+		c.Demuxer = ts.NewDemuxer(br)
+	default:
+		rc.Close()
+		return nil, fmt.Errorf("stream is neither mpegts nor flv")
+	}
+
+	return filterStreams(c), nil
+}
+
+func (c *streamConn) Streams() ([]av.CodecData, error) {
+	streams, err := c.Demuxer.Streams()
+
+	// live streams may pause, stalls are detected by the transmitter
+	if d, ok := c.rc.(readDeadliner); ok {
+		d.SetReadDeadline(time.Time{})
+	}
+
+	return streams, err
+}
+
+func (c *streamConn) Close() error {
+	return c.rc.Close()
+}
+
+// openStdin demuxes stdin, it can only be read once since a restarted
+// demuxer would start in the middle of the stream.
+func openStdin() (av.DemuxCloser, error) {
+	if !atomic.CompareAndSwapInt32(&stdinOpened, 0, 1) {
+		return nil, fmt.Errorf("stdin was already read")
+	}
+
+	return demux(os.Stdin)
+}
+
+// openTCP connects to a tcp server, e.g. ffmpeg with -f mpegts
+// tcp://0.0.0.0:port?listen.
+func openTCP(uri string) (av.DemuxCloser, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("tcp", u.Host, streamTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return demux(conn)
+}
+
+// openUDP receives datagrams on the address of uri, multicast groups are
+// joined.
+func openUDP(uri string) (av.DemuxCloser, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", u.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn *net.UDPConn
+	if addr.IP != nil && addr.IP.IsMulticast() {
+		conn, err = net.ListenMulticastUDP("udp", nil, addr)
+	} else {
+		conn, err = net.ListenUDP("udp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return demux(&datagramReader{UDPConn: conn, buf: make([]byte, maxDatagramSize)})
+}
+
+// datagramReader reads whole datagrams, reading into a short buffer would
+// truncate them.
+type datagramReader struct {
+	*net.UDPConn
+	buf  []byte
+	data []byte
+}
+
+func (r *datagramReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		n, err := r.UDPConn.Read(r.buf)
+		if err != nil {
+			return 0, err
+		}
+
+		r.data = r.buf[:n]
+	}
+
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+
+	return n, nil
+}
@@ -0,0 +1,371 @@
+package transmitter
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/format/rtmp"
+	"github.com/sirupsen/logrus"
+)
+
+const destinationQueueSize = 512
+
+// recordQueueSize buffers about a minute of a typical stream, so a slow
+// disk does not cost the recording any packets while a live destination
+// would rather drop than fall behind. On stop the queue is drained without
+// a timeout.
+const recordQueueSize = 8192
+
+type DestinationStats struct {
+	URL     string `json:"url"`
+	Primary bool   `json:"primary"`
+	Local   bool   `json:"local"`
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+	Dropped uint64 `json:"dropped"`
+	// DroppedGOPs counts queue overflows, each evicts the queued video of
+	// the newest gop and drops its remaining video.
+	DroppedGOPs uint64 `json:"dropped_gops"`
+	Reconnects  uint64 `json:"reconnects"`
+	// WriteLatency is a moving average of packet write durations.
+	WriteLatency time.Duration `json:"write_latency"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// destination writes packets to a single connection from its own queue,
+// so a slow destination drops its own packets instead of stalling the
+// source and the other destinations.
+type destination struct {
+	url     string
+	primary bool
+	dial    func() (av.MuxCloser, error)
+	// local destinations are files that are never closed before their
+	// trailer is written.
+	local  bool
+	logger *logrus.Entry
+
+	reconnect ReconnectConfig
+	stop      <-chan struct{}
+
+	streams      []av.CodecData
+	queue        *packetQueue
+	waitKeyFrame bool
+
+	// reopen is set when the first connect failed, Run reconnects before
+	// writing.
+	reopen bool
+
+	lock     sync.Mutex
+	conn     av.MuxCloser
+	stats    DestinationStats
+	failed   bool
+	dropping bool
+}
+
+func newDestination(url string, primary bool, logger *logrus.Entry) *destination {
+	return &destination{
+		url:     url,
+		primary: primary,
+		dial: func() (av.MuxCloser, error) {
+			return dialDestination(url)
+		},
+		logger: logger.WithField("destination", redactURL(url)),
+		queue:  newPacketQueue(destinationQueueSize),
+		stats:  DestinationStats{URL: redactURL(url), Primary: primary},
+	}
+}
+
+// redactURL hides the stream key of a destination url, the path after the
+// application name, the query and a password, so urls of third party
+// services can be logged.
+func redactURL(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Host == "" {
+		return uri
+	}
+
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+		}
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)
+	if len(parts) == 2 && parts[1] != "" {
+		u.Path = "/" + parts[0] + "/xxxxx"
+	}
+	u.RawPath = ""
+	if u.RawQuery != "" {
+		u.RawQuery = "xxxxx"
+	}
+	u.Fragment = ""
+
+	return u.String()
+}
+
+func newRecordDestination(c RecordConfig, create func(path string) (av.MuxCloser, error), logger *logrus.Entry) *destination {
+	d := newDestination(c.Path, false, logger)
+	d.dial = func() (av.MuxCloser, error) {
+		r := newRecorder(c)
+		r.create = create
+		return r, nil
+	}
+	d.queue = newPacketQueue(recordQueueSize)
+	d.local = true
+	d.stats.Local = true
+
+	return d
+}
+
+func (d *destination) Open(streams []av.CodecData) error {
+	d.streams = streams
+	d.queue.SetStreams(streams)
+
+	err := d.connect()
+	if err == nil {
+		return nil
+	}
+
+	// a simulcast destination that is down at start is reconnected like
+	// after a failed write
+	if !d.primary && !d.local && d.reconnect.MaxAttempts > 0 {
+		d.logger.WithError(err).Warn("failed to open destination, reconnecting")
+		d.reopen = true
+		return nil
+	}
+
+	return d.fail(err)
+}
+
+func (d *destination) connect() error {
+	conn, err := d.dial()
+	if err != nil {
+		return fmt.Errorf("failed to dial destination connection: %s", err.Error())
+	}
+
+	if err := conn.WriteHeader(d.streams); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to write header: %s", err.Error())
+	}
+
+	d.lock.Lock()
+	d.conn = conn
+	d.lock.Unlock()
+
+	return nil
+}
+
+// Send queues the packet and reports false when the packet was dropped
+// because the queue overflowed or a gop is being dropped.
+func (d *destination) Send(pkt av.Packet) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.failed {
+		return false
+	}
+
+	ok := d.queue.Push(pkt)
+
+	// a gap in a recording is permanent, report every overflow
+	if d.local && !ok && !d.dropping {
+		d.logger.Error("recording can not keep up with the source, dropping packets")
+	}
+	d.dropping = !ok
+
+	return ok
+}
+
+// Finish closes the queue, Run returns once queued packets are written.
+func (d *destination) Finish() {
+	d.queue.Close()
+}
+
+// SendHeader queues new codec data of the source, packets sent after it
+// are written with it.
+func (d *destination) SendHeader(streams []av.CodecData) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.failed {
+		return
+	}
+
+	d.queue.PushHeader(streams)
+}
+
+// Run writes queued packets until the queue is closed or a write fails and
+// the connection can not be reestablished, the trailer is written once the
+// queue is drained.
+func (d *destination) Run() error {
+	if d.reopen {
+		err := d.reconnect.retry(d.stop, d.logger, d.connect)
+		if err != nil {
+			return d.fail(err)
+		}
+
+		// packets queued meanwhile may start in the middle of a gop
+		d.waitKeyFrame = true
+
+		d.lock.Lock()
+		d.stats.Reconnects++
+		d.lock.Unlock()
+	}
+
+	for {
+		pkt, ok := d.queue.Pop()
+		if !ok {
+			break
+		}
+
+		d.lock.Lock()
+		conn := d.conn
+		d.lock.Unlock()
+
+		if pkt.streams != nil {
+			d.streams = pkt.streams
+			// a decoder needs a keyframe of the new codec data
+			d.waitKeyFrame = true
+
+			err := d.rewriteHeader(conn)
+			if err != nil {
+				err = d.recover(conn, fmt.Errorf("failed to rewrite destination header: %s", err))
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		// a packet of a stream the header did not announce can not be
+		// written, the muxers index their streams with it
+		if pkt.Idx < 0 || int(pkt.Idx) >= len(d.streams) {
+			d.logger.WithField("idx", pkt.Idx).Debug("dropped packet of an unknown stream")
+			d.lock.Lock()
+			d.stats.Dropped++
+			d.lock.Unlock()
+			continue
+		}
+
+		if d.waitKeyFrame {
+			if d.streams[pkt.Idx].Type().IsVideo() && !pkt.IsKeyFrame {
+				d.lock.Lock()
+				d.stats.Dropped++
+				d.lock.Unlock()
+				continue
+			}
+			d.waitKeyFrame = false
+		}
+
+		started := time.Now()
+		err := conn.WritePacket(pkt.Packet)
+		latency := time.Since(started)
+		if err != nil {
+			err = d.recover(conn, fmt.Errorf("write destination packet failed with error: %s", err))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		d.lock.Lock()
+		d.stats.Packets++
+		d.stats.Bytes += uint64(len(pkt.Data))
+		if d.stats.WriteLatency == 0 {
+			d.stats.WriteLatency = latency
+		} else {
+			d.stats.WriteLatency = (7*d.stats.WriteLatency + latency) / 8
+		}
+		d.lock.Unlock()
+	}
+
+	d.lock.Lock()
+	conn := d.conn
+	d.lock.Unlock()
+
+	err := conn.WriteTrailer()
+	if err != nil {
+		return d.fail(fmt.Errorf("failed to write trailer: %s", err))
+	}
+
+	return nil
+}
+
+// rewriteHeader sends the current streams on conn. Rtmp carries new codec
+// data in band and the recorder starts a new segment, other muxers would
+// write a second file header and are reconnected instead.
+func (d *destination) rewriteHeader(conn av.MuxCloser) error {
+	switch conn.(type) {
+	case *rtmp.Conn, *recorder:
+		return conn.WriteHeader(d.streams)
+	}
+
+	conn.WriteTrailer()
+	conn.Close()
+
+	return d.connect()
+}
+
+// recover reconnects after a failed write, it returns the error when the
+// destination can not be reconnected.
+func (d *destination) recover(conn av.MuxCloser, err error) error {
+	if d.local || d.reconnect.MaxAttempts == 0 {
+		return d.fail(err)
+	}
+
+	d.logger.WithError(err).Warn("destination connection lost")
+	conn.Close()
+
+	err = d.reconnect.retry(d.stop, d.logger, d.connect)
+	if err != nil {
+		return d.fail(err)
+	}
+
+	// a decoder can not resume in the middle of a gop
+	d.waitKeyFrame = true
+
+	d.lock.Lock()
+	d.stats.Reconnects++
+	d.lock.Unlock()
+
+	return nil
+}
+
+func (d *destination) fail(err error) error {
+	d.lock.Lock()
+	d.failed = true
+	d.stats.Error = err.Error()
+	d.lock.Unlock()
+
+	return err
+}
+
+func (d *destination) Close() {
+	d.lock.Lock()
+	conn := d.conn
+	d.lock.Unlock()
+
+	if conn == nil {
+		return
+	}
+
+	if err := conn.Close(); err != nil {
+		d.logger.WithError(err).Warn("failed to close destination connection")
+	}
+}
+
+func (d *destination) Stats() DestinationStats {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	stats := d.stats
+	dropped, droppedGOPs := d.queue.Dropped()
+	stats.Dropped += dropped
+	stats.DroppedGOPs = droppedGOPs
+
+	return stats
+}
@@ -0,0 +1,245 @@
+package transmitter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nareix/joy4/av"
+)
+
+// recovery is a reopened primary source positioned at a keyframe.
+type recovery struct {
+	conn    av.DemuxCloser
+	streams []av.CodecData
+	pkt     av.Packet
+}
+
+// openFirstSource opens the first source that can be opened in order.
+func (t *Transmitter) openFirstSource() ([]av.CodecData, error) {
+	var err error
+
+	for idx := range t.sources {
+		var streams []av.CodecData
+		streams, err = t.openSource(idx)
+		if err == nil || err == errStopped {
+			return streams, err
+		}
+
+		if len(t.sources) > 1 {
+			t.logger.WithError(err).WithField("source", idx).Warn("failed to open source")
+		}
+	}
+
+	return nil, err
+}
+
+func (t *Transmitter) activeSource() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.active
+}
+
+// switchSource opens the source at idx, its streams must match the streams
+// destinations were opened with.
+func (t *Transmitter) switchSource(idx int, streams []av.CodecData) error {
+	newStreams, err := t.openSource(idx)
+	if err != nil {
+		return err
+	}
+
+	if !compatibleStreams(streams, newStreams) {
+		t.closeSource()
+		return fmt.Errorf("source streams changed")
+	}
+
+	t.lock.Lock()
+	t.sourceReconnects++
+	t.lock.Unlock()
+
+	return nil
+}
+
+// reconnectSource switches to the next source that can be opened, the
+// failed source is tried last. Backups are tried right away, reconnect
+// delays only apply once every source failed.
+func (t *Transmitter) reconnectSource(streams []av.CodecData) error {
+	t.closeSource()
+
+	failed := t.activeSource()
+	logger := t.logger.WithField("source", failed)
+
+	connect := func() error {
+		var err error
+
+		for i := 1; i <= len(t.sources); i++ {
+			idx := (failed + i) % len(t.sources)
+
+			err = t.switchSource(idx, streams)
+			if err == errStopped {
+				return err
+			}
+			if err == nil {
+				if idx != failed {
+					t.logger.WithField("source", idx).Warn("switched to a backup source")
+				}
+				return nil
+			}
+
+			if len(t.sources) > 1 {
+				t.logger.WithError(err).WithField("source", idx).Warn("failed to open source")
+			}
+		}
+
+		return err
+	}
+
+	if len(t.sources) > 1 {
+		err := connect()
+		if err == nil || err == errStopped || t.reconnect.MaxAttempts == 0 {
+			return err
+		}
+	}
+
+	return t.reconnect.retry(t.halt, logger, connect)
+}
+
+// watchStall closes the source when no packet was read for the stall
+// timeout, the read error then fails the source over.
+func (t *Transmitter) watchStall() {
+	ticker := time.NewTicker(t.stallTimeout / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.halt:
+			return
+		case <-ticker.C:
+		}
+
+		t.lock.Lock()
+		var srcConn av.DemuxCloser
+		if time.Since(t.lastRead) > t.stallTimeout {
+			srcConn = t.srcConn
+			t.lastRead = time.Now()
+		}
+		active := t.active
+		t.lock.Unlock()
+
+		if srcConn != nil {
+			t.logger.WithField("source", active).Warnf("source stalled for %s", t.stallTimeout)
+			srcConn.Close()
+		}
+	}
+}
+
+// recoverPrimary reopens the primary source until it delivers a keyframe
+// and hands it over on recovered.
+func (t *Transmitter) recoverPrimary(streams []av.CodecData, recovered chan<- *recovery) {
+	logger := t.logger.WithField("source", 0)
+
+	for {
+		select {
+		case <-t.halt:
+			return
+		case <-time.After(t.recoverInterval):
+		}
+
+		r, err := t.probePrimary(streams)
+		if err != nil {
+			logger.WithError(err).Debug("primary source has not recovered")
+			continue
+		}
+
+		select {
+		case recovered <- r:
+		case <-t.halt:
+			r.conn.Close()
+		}
+		return
+	}
+}
+
+func (t *Transmitter) probePrimary(streams []av.CodecData) (*recovery, error) {
+	conn, err := t.open(t.sources[0])
+	if err != nil {
+		return nil, err
+	}
+
+	newStreams, err := conn.Streams()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if !compatibleStreams(streams, newStreams) {
+		conn.Close()
+		return nil, fmt.Errorf("source streams changed")
+	}
+
+	// a primary source that stalls has not recovered
+	timeout := t.stallTimeout
+	if timeout == 0 {
+		timeout = t.recoverInterval
+	}
+	timer := time.AfterFunc(timeout, func() {
+		conn.Close()
+	})
+
+	for {
+		pkt, err := conn.ReadPacket()
+		if err != nil {
+			timer.Stop()
+			conn.Close()
+			return nil, err
+		}
+
+		if hasVideo(streams) && !isVideoKeyFrame(streams, pkt) {
+			continue
+		}
+
+		if !timer.Stop() {
+			return nil, fmt.Errorf("no keyframe within %s", timeout)
+		}
+
+		return &recovery{conn: conn, streams: newStreams, pkt: pkt}, nil
+	}
+}
+
+// takeOver replaces a backup source with the recovered primary source,
+// it reports false and closes the recovered connection when the primary is
+// already active.
+func (t *Transmitter) takeOver(r *recovery) bool {
+	t.lock.Lock()
+	if t.active == 0 || t.srcClosed {
+		t.lock.Unlock()
+		r.conn.Close()
+		return false
+	}
+
+	backup := t.srcConn
+	t.srcConn = r.conn
+	t.srcStreams = r.streams
+	t.active = 0
+	t.lastRead = time.Now()
+	t.sourceReconnects++
+	t.lock.Unlock()
+
+	backup.Close()
+
+	return true
+}
+
+func hasVideo(streams []av.CodecData) bool {
+	for _, stream := range streams {
+		if stream.Type().IsVideo() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isVideoKeyFrame(streams []av.CodecData, pkt av.Packet) bool {
+	return int(pkt.Idx) < len(streams) && streams[pkt.Idx].Type().IsVideo() && pkt.IsKeyFrame
+}
@@ -0,0 +1,99 @@
+package transmitter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nareix/joy4/av"
+	"github.com/sirupsen/logrus"
+)
+
+func TestTransmitterFailover(t *testing.T) {
+	streams := []av.CodecData{testCodecData{av.H264}}
+	primary := newTestDemuxer(streams...)
+	recovered := newTestDemuxer(streams...)
+	backup := newTestDemuxer(streams...)
+	dst := &testMuxer{}
+
+	sources := map[string][]*testDemuxer{
+		"rtmp://127.0.0.1/primary": {primary, recovered},
+		"rtmp://127.0.0.1/backup":  {backup},
+	}
+
+	tr := NewTransmitter(TransmitterConfig{
+		Source:      "rtmp://127.0.0.1/primary",
+		Backups:     []string{"rtmp://127.0.0.1/backup"},
+		Destination: "rtmp://127.0.0.1/destination",
+		Logger:      logrus.NewEntry(logrus.New()),
+	})
+	tr.recoverInterval = 10 * time.Millisecond
+	tr.open = func(url string) (av.DemuxCloser, error) {
+		src := sources[url][0]
+		if len(sources[url]) > 1 {
+			sources[url] = sources[url][1:]
+		}
+		return src, nil
+	}
+	tr.dial = func(url string) (av.MuxCloser, error) { return dst, nil }
+
+	primary.packets <- av.Packet{Idx: 0, IsKeyFrame: true, Time: 0}
+	primary.packets <- av.Packet{Idx: 0, Time: 40 * time.Millisecond}
+	close(primary.packets)
+
+	// the backup joined mid gop is forwarded from its keyframe on
+	backup.packets <- av.Packet{Idx: 0, Time: 4 * time.Second}
+	backup.packets <- av.Packet{Idx: 0, IsKeyFrame: true, Time: 5 * time.Second}
+	go func() {
+		for i := 1; ; i++ {
+			select {
+			case backup.packets <- av.Packet{Idx: 0, Time: 5*time.Second + time.Duration(i)*40*time.Millisecond}:
+			case <-backup.closed:
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	recovered.packets <- av.Packet{Idx: 0, Time: 99 * time.Second}
+	recovered.packets <- av.Packet{Idx: 0, IsKeyFrame: true, Time: 100 * time.Second}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := tr.Start(ctx)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		stats := tr.Stats()
+		if stats.SourceReconnects == 2 && stats.ActiveSource == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("did not switch back to the primary source, got stats %+v", stats)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	if err := awaitError(t, errCh); err != nil {
+		t.Fatalf("got err: %s, want graceful stop", err)
+	}
+
+	if len(dst.packets) < 4 {
+		t.Fatalf("got %d packets, want at least 4", len(dst.packets))
+	}
+
+	if pkt := dst.packets[2]; !pkt.IsKeyFrame || pkt.Time != 40*time.Millisecond+rebaseGap {
+		t.Errorf("got first backup packet %+v, want keyframe at %s", pkt, 40*time.Millisecond+rebaseGap)
+	}
+
+	last := dst.packets[len(dst.packets)-1]
+	if !last.IsKeyFrame || last.Time > 10*time.Second {
+		t.Errorf("got last packet %+v, want the recovered primary keyframe on the same timeline", last)
+	}
+
+	for i := 1; i < len(dst.packets); i++ {
+		if dst.packets[i].Time < dst.packets[i-1].Time {
+			t.Errorf("packet %d at %s goes back from %s", i, dst.packets[i].Time, dst.packets[i-1].Time)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package transmitter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/format/flv"
+	"github.com/nareix/joy4/format/rtmp"
+)
+
+const (
+	httpFLVTimeout = 10 * time.Second
+	// httpFLVCloseTimeout bounds waiting for the ingest response once the
+	// request body ended.
+	httpFLVCloseTimeout = 2 * time.Second
+)
+
+var httpFLVClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         (&net.Dialer{Timeout: httpFLVTimeout}).DialContext,
+		TLSHandshakeTimeout: httpFLVTimeout,
+	},
+}
+
+// dialDestination connects to url by scheme, http and https urls are
+// http-flv ingest endpoints, anything else is rtmp.
+func dialDestination(url string) (av.MuxCloser, error) {
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		return dialHTTPFLV(url)
+	}
+
+	return rtmp.Dial(url)
+}
+
+// httpFLVConn pushes flv as the chunked body of a single post request
+// that lasts as long as the stream.
+type httpFLVConn struct {
+	*flv.Muxer
+	bw     *bufio.Writer
+	pw     *io.PipeWriter
+	cancel context.CancelFunc
+
+	done chan struct{}
+	err  error
+}
+
+// bodyReader reports when the transport started sending the request body,
+// the connection is established by then.
+type bodyReader struct {
+	*io.PipeReader
+	started chan struct{}
+	once    sync.Once
+}
+
+func (r *bodyReader) Read(p []byte) (int, error) {
+	r.once.Do(func() {
+		close(r.started)
+	})
+
+	return r.PipeReader.Read(p)
+}
+
+func dialHTTPFLV(url string) (av.MuxCloser, error) {
+	pr, pw := io.Pipe()
+	body := &bodyReader{PipeReader: pr, started: make(chan struct{})}
+
+	req, err := http.NewRequest(http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "video/x-flv")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req = req.WithContext(ctx)
+
+	bw := bufio.NewWriter(pw)
+	c := &httpFLVConn{
+		Muxer:  flv.NewMuxerWriteFlusher(bw),
+		bw:     bw,
+		pw:     pw,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(c.done)
+
+		resp, err := httpFLVClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+
+			if resp.StatusCode >= 300 {
+				err = fmt.Errorf("ingest refused the stream with %s", resp.Status)
+			}
+		}
+		c.err = err
+
+		// packet writes fail from now on
+		if err == nil {
+			err = fmt.Errorf("ingest ended the stream with %s", resp.Status)
+		}
+		pr.CloseWithError(err)
+	}()
+
+	timer := time.NewTimer(httpFLVTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-body.started:
+		return c, nil
+	case <-c.done:
+		cancel()
+		if c.err == nil {
+			return nil, fmt.Errorf("ingest ended the stream before it started")
+		}
+		return nil, c.err
+	case <-timer.C:
+		c.Close()
+		return nil, fmt.Errorf("timed out connecting to %s", req.URL.Host)
+	}
+}
+
+// WriteHeader and WritePacket flush every tag, at low bitrates the ingest
+// would otherwise wait for a buffer to fill.
+func (c *httpFLVConn) WriteHeader(streams []av.CodecData) error {
+	if err := c.Muxer.WriteHeader(streams); err != nil {
+		return err
+	}
+
+	return c.bw.Flush()
+}
+
+func (c *httpFLVConn) WritePacket(pkt av.Packet) error {
+	if err := c.Muxer.WritePacket(pkt); err != nil {
+		return err
+	}
+
+	return c.bw.Flush()
+}
+
+// Close ends the request body and gives the ingest a moment to respond
+// before the request is aborted, it returns the error of the request.
+func (c *httpFLVConn) Close() error {
+	c.pw.Close()
+
+	select {
+	case <-c.done:
+		c.cancel()
+		return c.err
+	case <-time.After(httpFLVCloseTimeout):
+		c.cancel()
+		return fmt.Errorf("ingest did not respond within %s", httpFLVCloseTimeout)
+	}
+}
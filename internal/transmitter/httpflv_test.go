@@ -0,0 +1,119 @@
+package transmitter
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nareix/joy4/av"
+)
+
+func TestHTTPFLVPush(t *testing.T) {
+	header := make(chan []byte, 1)
+	received := make(chan []byte, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "video/x-flv" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		b := make([]byte, 9)
+		if _, err := io.ReadFull(r.Body, b); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		header <- b
+
+		rest, _ := ioutil.ReadAll(r.Body)
+		received <- rest
+	}))
+	defer server.Close()
+
+	conn, err := dialDestination(server.URL + "/live/key.flv")
+	if err != nil {
+		t.Fatalf("failed to dial: %s", err)
+	}
+
+	if err := conn.WriteHeader([]av.CodecData{testH264CodecData(t, testSPS720p)}); err != nil {
+		t.Fatalf("failed to write header: %s", err)
+	}
+
+	// the header is flushed right away, not with the trailer
+	select {
+	case b := <-header:
+		if !bytes.HasPrefix(b, []byte("FLV")) {
+			t.Errorf("body is not flv, got: %q", b)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("ingest did not receive the header before the trailer")
+	}
+
+	if err := conn.WritePacket(av.Packet{IsKeyFrame: true, Data: []byte{0, 0, 0, 1, 0x65}}); err != nil {
+		t.Fatalf("failed to write packet: %s", err)
+	}
+	if err := conn.WriteTrailer(); err != nil {
+		t.Fatalf("failed to write trailer: %s", err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Errorf("Close failed with err: %s", err)
+	}
+
+	select {
+	case b := <-received:
+		if len(b) == 0 {
+			t.Errorf("ingest did not receive the packet")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("ingest did not receive the stream")
+	}
+}
+
+func TestHTTPFLVRefused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	defer ln.Close()
+
+	// the ingest refuses the stream without reading the body
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte("HTTP/1.1 403 Forbidden\r\nContent-Length: 0\r\nConnection: close\r\n\r\n"))
+	}()
+
+	conn, err := dialDestination("http://" + ln.Addr().String() + "/live/key.flv")
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// the transport started sending the body before the response arrived
+	conn.WriteHeader([]av.CodecData{testH264CodecData(t, testSPS720p)})
+
+	c := conn.(*httpFLVConn)
+	select {
+	case <-c.done:
+	case <-time.After(time.Second):
+		t.Fatalf("refused push was not detected")
+	}
+
+	if c.err == nil {
+		t.Errorf("expected an error for a refused push")
+	}
+	if err := conn.WritePacket(av.Packet{Data: []byte{1}}); err == nil {
+		t.Errorf("expected packet writes to fail after the push was refused")
+	}
+}
@@ -0,0 +1,179 @@
+package transmitter
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/format/rtmp"
+	"github.com/sirupsen/logrus"
+)
+
+// Ingest accepts rtmp publishes, so encoders can push to the cli instead
+// of the cli pulling from an rtmp server. One publisher is relayed at a
+// time, others are rejected until it disconnects.
+type Ingest struct {
+	addr   string
+	path   string
+	logger *logrus.Entry
+
+	publishers chan *publishConn
+	serveErr   chan error
+
+	lock    sync.Mutex
+	active  *publishConn
+	pending *publishConn
+	closed  bool
+}
+
+// publishConn is closed by the transmitter, the publish handler keeps the
+// connection open until then.
+type publishConn struct {
+	av.DemuxCloser
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *publishConn) Close() error {
+	err := c.DemuxCloser.Close()
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+
+	return err
+}
+
+// NewIngest parses listen as host:port followed by an optional path, e.g.
+// :1935/live/key, publishes to other paths are rejected.
+func NewIngest(listen string, logger *logrus.Entry) (*Ingest, error) {
+	addr, path := listen, ""
+	if i := strings.Index(listen, "/"); i >= 0 {
+		addr, path = listen[:i], listen[i:]
+	}
+
+	_, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid listen address: %q", listen)
+	}
+
+	return &Ingest{
+		addr:       addr,
+		path:       path,
+		logger:     logger.WithField("component", "ingest"),
+		publishers: make(chan *publishConn, 1),
+		serveErr:   make(chan error, 1),
+	}, nil
+}
+
+// URL is where encoders publish to.
+func (i *Ingest) URL() string {
+	host, port, _ := net.SplitHostPort(i.addr)
+	if host == "" {
+		host = "localhost"
+	}
+
+	return fmt.Sprintf("rtmp://%s%s", net.JoinHostPort(host, port), i.path)
+}
+
+// Listen serves rtmp in the background, a failure is returned by the next
+// Accept.
+func (i *Ingest) Listen() {
+	server := &rtmp.Server{
+		Addr: i.addr,
+		HandlePublish: func(conn *rtmp.Conn) {
+			i.handlePublish(conn.URL.Path, conn)
+		},
+	}
+
+	go func() {
+		err := server.ListenAndServe()
+		i.serveErr <- fmt.Errorf("failed to listen on %s: %s", i.addr, err)
+	}()
+
+	i.logger.Infof("listening on %s", i.URL())
+}
+
+func (i *Ingest) handlePublish(path string, conn av.DemuxCloser) {
+	logger := i.logger.WithField("path", path)
+
+	if i.path != "" && path != i.path {
+		logger.Warn("rejected publish to an unknown path")
+		return
+	}
+
+	pc := &publishConn{DemuxCloser: conn, done: make(chan struct{})}
+
+	i.lock.Lock()
+	if i.closed || i.active != nil {
+		i.lock.Unlock()
+		logger.Warn("rejected publish, another publisher is connected")
+		return
+	}
+	i.active = pc
+	i.lock.Unlock()
+
+	logger.Info("publisher connected")
+	i.publishers <- pc
+
+	<-pc.done
+
+	i.lock.Lock()
+	i.active = nil
+	i.lock.Unlock()
+
+	logger.Info("publisher disconnected")
+}
+
+// Wait blocks until a publisher connects, the next Accept returns it.
+func (i *Ingest) Wait(stop <-chan struct{}) error {
+	pc, err := i.accept(stop)
+	if err != nil {
+		return err
+	}
+
+	i.lock.Lock()
+	i.pending = pc
+	i.lock.Unlock()
+
+	return nil
+}
+
+// Accept returns the connection of the next publisher.
+func (i *Ingest) Accept(stop <-chan struct{}) (av.DemuxCloser, error) {
+	i.lock.Lock()
+	pc := i.pending
+	i.pending = nil
+	i.lock.Unlock()
+
+	if pc != nil {
+		return pc, nil
+	}
+
+	return i.accept(stop)
+}
+
+func (i *Ingest) accept(stop <-chan struct{}) (*publishConn, error) {
+	select {
+	case pc := <-i.publishers:
+		return pc, nil
+	case err := <-i.serveErr:
+		i.serveErr <- err
+		return nil, err
+	case <-stop:
+		return nil, errStopped
+	}
+}
+
+// Close rejects further publishers and disconnects the active one.
+func (i *Ingest) Close() {
+	i.lock.Lock()
+	i.closed = true
+	active := i.active
+	i.lock.Unlock()
+
+	if active != nil {
+		active.Close()
+	}
+}
@@ -0,0 +1,74 @@
+package transmitter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nareix/joy4/av"
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewIngest(t *testing.T) {
+	tables := []struct {
+		listen string
+		url    string
+		valid  bool
+	}{
+		{":1935/live/key", "rtmp://localhost:1935/live/key", true},
+		{"0.0.0.0:1935", "rtmp://0.0.0.0:1935", true},
+		{"1935/live", "", false},
+	}
+
+	for _, table := range tables {
+		i, err := NewIngest(table.listen, logrus.NewEntry(logrus.New()))
+		if (err == nil) != table.valid {
+			t.Errorf("%s: got err %v, want valid %t", table.listen, err, table.valid)
+			continue
+		}
+
+		if err == nil && i.URL() != table.url {
+			t.Errorf("%s: got url %s, want %s", table.listen, i.URL(), table.url)
+		}
+	}
+}
+
+func TestIngestPublish(t *testing.T) {
+	i, err := NewIngest(":1935/live/key", logrus.NewEntry(logrus.New()))
+	if err != nil {
+		t.Fatalf("NewIngest failed with err: %s", err)
+	}
+
+	handled := make(chan struct{})
+	first := newTestDemuxer(testCodecData{av.H264})
+	go func() {
+		i.handlePublish("/live/key", first)
+		close(handled)
+	}()
+
+	if err := i.Wait(nil); err != nil {
+		t.Fatalf("Wait failed with err: %s", err)
+	}
+
+	// a second publisher is rejected while the first one is relayed
+	rejected := newTestDemuxer(testCodecData{av.H264})
+	i.handlePublish("/live/key", rejected)
+	i.handlePublish("/live/other", rejected)
+
+	conn, err := i.Accept(nil)
+	if err != nil {
+		t.Fatalf("Accept failed with err: %s", err)
+	}
+
+	conn.Close()
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatalf("publish handler did not return after the connection was closed")
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	if _, err := i.Accept(stop); err != errStopped {
+		t.Errorf("got err %v, want errStopped without a publisher", err)
+	}
+}
@@ -0,0 +1,54 @@
+package transmitter
+
+import (
+	"time"
+)
+
+// pacer holds packets back until their timestamp is due on the wall clock,
+// so a file is sent at the rate it would be played back.
+type pacer struct {
+	now func() time.Time
+
+	started bool
+	start   time.Time
+	first   time.Duration
+}
+
+func newPacer() *pacer {
+	return &pacer{now: time.Now}
+}
+
+// Delay returns how long to wait before sending a packet with timestamp ts,
+// packets that are late are not delayed.
+func (p *pacer) Delay(ts time.Duration) time.Duration {
+	if !p.started {
+		p.start = p.now()
+		p.first = ts
+		p.started = true
+	}
+
+	delay := p.start.Add(ts - p.first).Sub(p.now())
+	if delay < 0 {
+		return 0
+	}
+
+	return delay
+}
+
+// Wait blocks until a packet with timestamp ts is due or stop is closed.
+func (p *pacer) Wait(ts time.Duration, stop <-chan struct{}) error {
+	delay := p.Delay(ts)
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-stop:
+		return errStopped
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,34 @@
+package transmitter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPacerDelay(t *testing.T) {
+	now := time.Unix(0, 0)
+
+	p := newPacer()
+	p.now = func() time.Time { return now }
+
+	tables := []struct {
+		elapsed time.Duration
+		ts      time.Duration
+		delay   time.Duration
+	}{
+		{0, 5 * time.Second, 0},
+		{0, 5*time.Second + 40*time.Millisecond, 40 * time.Millisecond},
+		{40 * time.Millisecond, 5*time.Second + 80*time.Millisecond, 40 * time.Millisecond},
+		{time.Second, 5*time.Second + 80*time.Millisecond, 0},
+		{time.Second, 7 * time.Second, time.Second},
+	}
+
+	for i, table := range tables {
+		now = time.Unix(0, 0).Add(table.elapsed)
+
+		delay := p.Delay(table.ts)
+		if delay != table.delay {
+			t.Errorf("Test %d Delay is incorrect, got: %s, want: %s", i, delay, table.delay)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package transmitter
+
+import (
+	"sync"
+
+	"github.com/nareix/joy4/av"
+)
+
+// packetQueue buffers packets between the source reader and a destination
+// writer. When it overflows the video of the newest gop is evicted, also
+// the part that is already queued, and video resumes on the next keyframe,
+// so a decoder never receives a gop with missing frames. Audio is kept
+// while there is room, viewers keep hearing the stream while video skips.
+type packetQueue struct {
+	lock    sync.Mutex
+	packets []queuedPacket
+	size    int
+	ready   chan struct{}
+	closed  bool
+
+	video    []bool
+	hasVideo bool
+	dropping bool
+
+	dropped     uint64
+	droppedGOPs uint64
+}
+
+// queuedPacket is a packet or, when streams is set, new codec data the
+// packets after it are written with.
+type queuedPacket struct {
+	av.Packet
+	streams []av.CodecData
+}
+
+func newPacketQueue(size int) *packetQueue {
+	return &packetQueue{
+		size:  size,
+		ready: make(chan struct{}, 1),
+	}
+}
+
+func (q *packetQueue) SetStreams(streams []av.CodecData) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.setStreams(streams)
+}
+
+func (q *packetQueue) setStreams(streams []av.CodecData) {
+	q.video = make([]bool, len(streams))
+	q.hasVideo = false
+	for i, stream := range streams {
+		q.video[i] = stream.Type().IsVideo()
+		q.hasVideo = q.hasVideo || q.video[i]
+	}
+}
+
+func (q *packetQueue) isVideo(pkt av.Packet) bool {
+	return int(pkt.Idx) < len(q.video) && q.video[pkt.Idx]
+}
+
+func (q *packetQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// PushHeader queues new codec data, it is never dropped, even when the
+// queue is full.
+func (q *packetQueue) PushHeader(streams []av.CodecData) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.closed {
+		return
+	}
+
+	q.setStreams(streams)
+	q.packets = append(q.packets, queuedPacket{streams: streams})
+	q.signal()
+}
+
+// Push queues the packet without blocking and reports false when it was
+// dropped.
+func (q *packetQueue) Push(pkt av.Packet) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.closed {
+		return false
+	}
+
+	if q.dropping && q.isVideo(pkt) {
+		if !pkt.IsKeyFrame {
+			q.dropped++
+			return false
+		}
+		q.dropping = false
+	}
+
+	if len(q.packets) >= q.size && q.hasVideo {
+		q.evictGOP()
+
+		if q.isVideo(pkt) && !pkt.IsKeyFrame {
+			q.dropping = true
+		}
+	}
+
+	if len(q.packets) >= q.size || (q.dropping && q.isVideo(pkt)) {
+		q.dropped++
+		return false
+	}
+
+	q.packets = append(q.packets, queuedPacket{Packet: pkt})
+	q.signal()
+
+	return true
+}
+
+// evictGOP removes the queued video of the newest gop. When the writer
+// already took its keyframe all queued video belongs to it, the gop then
+// ends early but never misses frames in between.
+func (q *packetQueue) evictGOP() {
+	start := 0
+	for i := len(q.packets) - 1; i >= 0; i-- {
+		if q.packets[i].streams == nil && q.isVideo(q.packets[i].Packet) && q.packets[i].IsKeyFrame {
+			start = i
+			break
+		}
+	}
+
+	kept := q.packets[:start]
+	for _, pkt := range q.packets[start:] {
+		if pkt.streams != nil || !q.isVideo(pkt.Packet) {
+			kept = append(kept, pkt)
+		}
+	}
+
+	evicted := len(q.packets) - len(kept)
+	if evicted == 0 {
+		return
+	}
+
+	for i := len(kept); i < len(q.packets); i++ {
+		q.packets[i] = queuedPacket{}
+	}
+	q.packets = kept
+	q.dropped += uint64(evicted)
+	q.droppedGOPs++
+}
+
+// Pop blocks until a packet or header is queued, it reports false once the
+// queue is closed and drained.
+func (q *packetQueue) Pop() (queuedPacket, bool) {
+	for {
+		q.lock.Lock()
+		if len(q.packets) > 0 {
+			pkt := q.packets[0]
+			q.packets[0] = queuedPacket{}
+			q.packets = q.packets[1:]
+			q.lock.Unlock()
+
+			return pkt, true
+		}
+
+		closed := q.closed
+		q.lock.Unlock()
+
+		if closed {
+			return queuedPacket{}, false
+		}
+
+		<-q.ready
+	}
+}
+
+// Dropped returns the dropped packets and evicted gops.
+func (q *packetQueue) Dropped() (uint64, uint64) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.dropped, q.droppedGOPs
+}
+
+func (q *packetQueue) Close() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.closed {
+		return
+	}
+	q.closed = true
+	close(q.ready)
+}
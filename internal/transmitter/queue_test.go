@@ -0,0 +1,114 @@
+package transmitter
+
+import (
+	"testing"
+
+	"github.com/nareix/joy4/av"
+)
+
+func popAll(q *packetQueue) []av.Packet {
+	q.Close()
+
+	var packets []av.Packet
+	for {
+		pkt, ok := q.Pop()
+		if !ok {
+			return packets
+		}
+		packets = append(packets, pkt.Packet)
+	}
+}
+
+func TestPacketQueueDropsGOPs(t *testing.T) {
+	q := newPacketQueue(5)
+	q.SetStreams([]av.CodecData{testCodecData{av.H264}, testCodecData{av.AAC}})
+
+	tables := []struct {
+		pkt    av.Packet
+		queued bool
+	}{
+		{av.Packet{Idx: 0, IsKeyFrame: true, Time: 0}, true},
+		{av.Packet{Idx: 0, Time: 1}, true},
+		// the second gop is half queued when the queue overflows
+		{av.Packet{Idx: 0, IsKeyFrame: true, Time: 2}, true},
+		{av.Packet{Idx: 1, Time: 2}, true},
+		{av.Packet{Idx: 0, Time: 3}, true},
+		{av.Packet{Idx: 0, Time: 4}, false},
+		// the rest of its video is dropped, audio is kept
+		{av.Packet{Idx: 1, Time: 4}, true},
+		{av.Packet{Idx: 0, Time: 5}, false},
+		{av.Packet{Idx: 0, IsKeyFrame: true, Time: 6}, true},
+	}
+
+	for i, table := range tables {
+		if queued := q.Push(table.pkt); queued != table.queued {
+			t.Errorf("Test %d Push is incorrect, got: %t, want: %t", i, queued, table.queued)
+		}
+	}
+
+	want := []av.Packet{
+		{Idx: 0, IsKeyFrame: true, Time: 0},
+		{Idx: 0, Time: 1},
+		{Idx: 1, Time: 2},
+		{Idx: 1, Time: 4},
+		{Idx: 0, IsKeyFrame: true, Time: 6},
+	}
+
+	got := popAll(q)
+	if len(got) != len(want) {
+		t.Fatalf("queued packets are incorrect, got: %v, want: %v", got, want)
+	}
+	for i := range want {
+		if got[i].Idx != want[i].Idx || got[i].Time != want[i].Time {
+			t.Errorf("packet %d is incorrect, got: %v, want: %v", i, got[i], want[i])
+		}
+	}
+
+	// the two evicted and the two dropped video packets of the second gop
+	if dropped, droppedGOPs := q.Dropped(); dropped != 4 || droppedGOPs != 1 {
+		t.Errorf("got %d dropped packets and %d dropped gops, want 4 and 1", dropped, droppedGOPs)
+	}
+}
+
+func TestPacketQueueWriterInGOP(t *testing.T) {
+	q := newPacketQueue(2)
+	q.SetStreams([]av.CodecData{testCodecData{av.H264}})
+
+	q.Push(av.Packet{Idx: 0, IsKeyFrame: true})
+	q.Push(av.Packet{Idx: 0, Time: 1})
+
+	// the writer took the keyframe, the queued rest of the gop is evicted
+	q.Pop()
+	q.Push(av.Packet{Idx: 0, Time: 2})
+
+	if q.Push(av.Packet{Idx: 0, Time: 3}) {
+		t.Errorf("video queued after an overflow in the middle of a gop")
+	}
+	if !q.Push(av.Packet{Idx: 0, IsKeyFrame: true, Time: 4}) {
+		t.Errorf("keyframe of the next gop was dropped")
+	}
+
+	got := popAll(q)
+	if len(got) != 1 || !got[0].IsKeyFrame {
+		t.Errorf("queued packets are incorrect, got: %v, want only the next keyframe", got)
+	}
+}
+
+func TestPacketQueueWithoutVideo(t *testing.T) {
+	q := newPacketQueue(1)
+	q.SetStreams([]av.CodecData{testCodecData{av.AAC}})
+
+	q.Push(av.Packet{Idx: 0})
+	if q.Push(av.Packet{Idx: 0}) {
+		t.Errorf("packet queued in a full queue")
+	}
+
+	q.Pop()
+	if !q.Push(av.Packet{Idx: 0}) {
+		t.Errorf("audio only queue kept dropping after overflow")
+	}
+
+	if dropped, droppedGOPs := q.Dropped(); dropped != 1 || droppedGOPs != 0 {
+		t.Errorf("got %d dropped packets and %d dropped gops, want 1 and 0", dropped, droppedGOPs)
+	}
+}
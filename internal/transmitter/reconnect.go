@@ -0,0 +1,77 @@
+package transmitter
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var errStopped = errors.New("transmitter stopped")
+
+type ReconnectConfig struct {
+	// MaxAttempts limits consecutive reconnect attempts, zero disables
+	// reconnects.
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+// Delay doubles the initial delay for every attempt up to the max delay
+// and adds jitter, so reconnecting clients do not retry in lockstep. A zero
+// initial delay retries immediately.
+func (c ReconnectConfig) Delay(attempt int) time.Duration {
+	if c.InitialDelay <= 0 {
+		return 0
+	}
+
+	delay := c.MaxDelay
+	if attempt < 32 {
+		d := c.InitialDelay << uint(attempt)
+		if d > 0 && d < c.MaxDelay {
+			delay = d
+		}
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retry calls connect until it succeeds, the attempts are exhausted, stop
+// is closed or connect reports errStopped.
+func (c ReconnectConfig) retry(stop <-chan struct{}, logger *logrus.Entry, connect func() error) error {
+	var err error
+
+	for attempt := 0; attempt < c.MaxAttempts; attempt++ {
+		delay := c.Delay(attempt)
+		logger.WithFields(logrus.Fields{
+			"attempt": attempt + 1,
+			"delay":   delay,
+		}).Warn("reconnecting")
+
+		select {
+		case <-stop:
+			return errStopped
+		case <-time.After(delay):
+		}
+
+		err = connect()
+		if err == nil {
+			logger.WithField("attempt", attempt+1).Info("reconnected")
+			return nil
+		}
+		if err == errStopped {
+			return err
+		}
+
+		logger.WithError(err).WithField("attempt", attempt+1).Warn("failed to reconnect")
+	}
+
+	if err == nil {
+		return errors.New("reconnects are disabled")
+	}
+
+	return fmt.Errorf("failed to reconnect after %d attempts: %s", c.MaxAttempts, err.Error())
+}
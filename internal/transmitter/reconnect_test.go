@@ -0,0 +1,93 @@
+package transmitter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestReconnectDelay(t *testing.T) {
+	c := ReconnectConfig{
+		MaxAttempts:  10,
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     time.Second,
+	}
+	immediate := ReconnectConfig{
+		MaxAttempts: 10,
+		MaxDelay:    time.Second,
+	}
+
+	tables := []struct {
+		config  ReconnectConfig
+		attempt int
+		max     time.Duration
+	}{
+		{c, 0, 100 * time.Millisecond},
+		{c, 1, 200 * time.Millisecond},
+		{c, 3, 800 * time.Millisecond},
+		{c, 4, time.Second},
+		{c, 100, time.Second},
+		{immediate, 0, 0},
+		{immediate, 5, 0},
+	}
+
+	for i, table := range tables {
+		delay := table.config.Delay(table.attempt)
+		if delay < table.max/2 || delay > table.max {
+			t.Errorf("Test %d Delay is incorrect, got: %s, want between %s and %s", i, delay, table.max/2, table.max)
+		}
+	}
+}
+
+func TestReconnectRetry(t *testing.T) {
+	c := ReconnectConfig{
+		MaxAttempts:  3,
+		InitialDelay: time.Millisecond,
+		MaxDelay:     time.Millisecond,
+	}
+	logger := logrus.NewEntry(logrus.New())
+
+	attempts := 0
+	err := c.retry(nil, logger, func() error {
+		attempts++
+		if attempts < 2 {
+			return errors.New("refused")
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Errorf("retry got err: %v after %d attempts, want success after 2", err, attempts)
+	}
+
+	attempts = 0
+	err = c.retry(nil, logger, func() error {
+		attempts++
+		return errors.New("refused")
+	})
+	if err == nil || attempts != 3 {
+		t.Errorf("retry got err: %v after %d attempts, want failure after 3", err, attempts)
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	err = c.retry(stop, logger, func() error { return nil })
+	if err != errStopped {
+		t.Errorf("retry got err: %v, want: %v", err, errStopped)
+	}
+
+	attempts = 0
+	err = c.retry(nil, logger, func() error {
+		attempts++
+		return errStopped
+	})
+	if err != errStopped || attempts != 1 {
+		t.Errorf("retry got err: %v after %d attempts, want: %v after 1", err, attempts, errStopped)
+	}
+
+	err = ReconnectConfig{}.retry(nil, logger, func() error { return nil })
+	if err == nil {
+		t.Errorf("retry with disabled reconnects succeeded")
+	}
+}
@@ -0,0 +1,204 @@
+package transmitter
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/av/avutil"
+)
+
+type RecordConfig struct {
+	// Path of the recording, the extension selects the FLV or MP4 muxer.
+	// Segments and files after a codec change of the source are numbered.
+	Path string
+	// SegmentDuration and SegmentSize start a new segment on the next
+	// keyframe once exceeded, zero disables rotation.
+	SegmentDuration time.Duration
+	SegmentSize     int64
+}
+
+func (c *RecordConfig) Validate() error {
+	switch strings.ToLower(filepath.Ext(c.Path)) {
+	case ".flv", ".mp4":
+	default:
+		return fmt.Errorf("not a supported recording format: %q, must be .flv or .mp4", c.Path)
+	}
+
+	if c.SegmentDuration < 0 || c.SegmentSize < 0 {
+		return fmt.Errorf("segment duration and size must not be negative")
+	}
+
+	return nil
+}
+
+func (c *RecordConfig) rotates() bool {
+	return c.SegmentDuration > 0 || c.SegmentSize > 0
+}
+
+// recorder muxes packets into local files, rotating segments on keyframes
+// and rebasing timestamps so every segment starts at zero.
+type recorder struct {
+	config RecordConfig
+	create func(path string) (av.MuxCloser, error)
+
+	lock sync.Mutex
+
+	streams  []av.CodecData
+	hasVideo bool
+
+	muxer   av.MuxCloser
+	index   int
+	started bool
+	start   time.Duration
+	size    int64
+}
+
+func newRecorder(c RecordConfig) *recorder {
+	return &recorder{
+		config: c,
+		create: avutil.Create,
+	}
+}
+
+func (r *recorder) segmentPath() string {
+	if !r.config.rotates() && r.index == 1 {
+		return r.config.Path
+	}
+
+	ext := filepath.Ext(r.config.Path)
+	base := strings.TrimSuffix(r.config.Path, ext)
+
+	return fmt.Sprintf("%s-%05d%s", base, r.index, ext)
+}
+
+func (r *recorder) openSegment() error {
+	r.index++
+
+	muxer, err := r.create(r.segmentPath())
+	if err != nil {
+		return fmt.Errorf("failed to create recording: %s", err.Error())
+	}
+
+	err = muxer.WriteHeader(r.streams)
+	if err != nil {
+		muxer.Close()
+		return fmt.Errorf("failed to write recording header: %s", err.Error())
+	}
+
+	r.muxer = muxer
+	r.started = false
+	r.size = 0
+
+	return nil
+}
+
+func (r *recorder) closeSegment() error {
+	if r.muxer == nil {
+		return nil
+	}
+
+	err := r.muxer.WriteTrailer()
+	closeErr := r.muxer.Close()
+	r.muxer = nil
+
+	if err != nil {
+		return fmt.Errorf("failed to write recording trailer: %s", err.Error())
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to close recording: %s", closeErr.Error())
+	}
+
+	return nil
+}
+
+// WriteHeader opens the first segment, later calls with new codec data of
+// the source start a new segment.
+func (r *recorder) WriteHeader(streams []av.CodecData) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if err := r.closeSegment(); err != nil {
+		return err
+	}
+
+	r.streams = streams
+	r.hasVideo = false
+	for _, stream := range streams {
+		if stream.Type().IsVideo() {
+			r.hasVideo = true
+		}
+	}
+
+	return r.openSegment()
+}
+
+func (r *recorder) shouldRotate(pkt av.Packet) bool {
+	if !r.started || !r.config.rotates() {
+		return false
+	}
+
+	if r.hasVideo && !(pkt.IsKeyFrame && r.streams[pkt.Idx].Type().IsVideo()) {
+		return false
+	}
+
+	if r.config.SegmentDuration > 0 && pkt.Time-r.start >= r.config.SegmentDuration {
+		return true
+	}
+
+	return r.config.SegmentSize > 0 && r.size >= r.config.SegmentSize
+}
+
+func (r *recorder) WritePacket(pkt av.Packet) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.muxer == nil {
+		return fmt.Errorf("recording is closed")
+	}
+
+	if r.shouldRotate(pkt) {
+		err := r.closeSegment()
+		if err != nil {
+			return err
+		}
+
+		err = r.openSegment()
+		if err != nil {
+			return err
+		}
+	}
+
+	if !r.started {
+		r.start = pkt.Time
+		r.started = true
+	}
+
+	pkt.Time -= r.start
+	if pkt.Time < 0 {
+		pkt.Time = 0
+	}
+
+	r.size += int64(len(pkt.Data))
+
+	return r.muxer.WritePacket(pkt)
+}
+
+func (r *recorder) WriteTrailer() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.closeSegment()
+}
+
+// Close writes the trailer of an open segment, so the file plays also when
+// the recording was not finished.
+func (r *recorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.closeSegment()
+}
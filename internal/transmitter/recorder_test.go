@@ -0,0 +1,133 @@
+package transmitter
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/codec/h264parser"
+)
+
+type testCodecData struct {
+	codecType av.CodecType
+}
+
+func (c testCodecData) Type() av.CodecType {
+	return c.codecType
+}
+
+const (
+	testSPS720p = "6764001facd9405005bb0110000003001000000303c0f1831960"
+	testSPS480p = "6742c01e95a0280f6c0440000003004000000c83c58b8980"
+)
+
+// testH264CodecData parses real codec data for muxers that need more than
+// the codec type.
+func testH264CodecData(t *testing.T, sps string) av.CodecData {
+	b, err := hex.DecodeString(sps)
+	if err != nil {
+		t.Fatalf("invalid sps: %s", err)
+	}
+
+	c, err := h264parser.NewCodecDataFromSPSAndPPS(b, []byte{0x68, 0xeb, 0xe3, 0xcb, 0x22, 0xc0})
+	if err != nil {
+		t.Fatalf("failed to parse sps: %s", err)
+	}
+
+	return c
+}
+
+type testMuxer struct {
+	path    string
+	headers [][]av.CodecData
+	packets []av.Packet
+	trailer bool
+}
+
+func (m *testMuxer) WriteHeader(streams []av.CodecData) error {
+	m.headers = append(m.headers, streams)
+	return nil
+}
+func (m *testMuxer) WritePacket(pkt av.Packet) error { m.packets = append(m.packets, pkt); return nil }
+func (m *testMuxer) WriteTrailer() error             { m.trailer = true; return nil }
+func (m *testMuxer) Close() error                    { return nil }
+
+func TestRecorderRotation(t *testing.T) {
+	var muxers []*testMuxer
+
+	r := newRecorder(RecordConfig{Path: "/tmp/archive.flv", SegmentDuration: 2 * time.Second})
+	r.create = func(path string) (av.MuxCloser, error) {
+		m := &testMuxer{path: path}
+		muxers = append(muxers, m)
+		return m, nil
+	}
+
+	streams := []av.CodecData{testCodecData{av.H264}, testCodecData{av.AAC}}
+	if err := r.WriteHeader(streams); err != nil {
+		t.Fatalf("WriteHeader failed with err: %s", err)
+	}
+
+	packets := []av.Packet{
+		{Idx: 0, IsKeyFrame: true, Time: 10 * time.Second},
+		{Idx: 1, Time: 11 * time.Second},
+		{Idx: 1, Time: 12 * time.Second},
+		{Idx: 0, Time: 12 * time.Second},
+		{Idx: 0, IsKeyFrame: true, Time: 13 * time.Second},
+		{Idx: 1, Time: 13 * time.Second},
+	}
+	for i, pkt := range packets {
+		if err := r.WritePacket(pkt); err != nil {
+			t.Fatalf("Test %d WritePacket failed with err: %s", i, err)
+		}
+	}
+
+	if err := r.WriteTrailer(); err != nil {
+		t.Fatalf("WriteTrailer failed with err: %s", err)
+	}
+
+	if len(muxers) != 2 {
+		t.Fatalf("got %d segments, want 2", len(muxers))
+	}
+
+	tables := []struct {
+		path    string
+		packets int
+		first   time.Duration
+	}{
+		{"/tmp/archive-00001.flv", 4, 0},
+		{"/tmp/archive-00002.flv", 2, 0},
+	}
+
+	for i, table := range tables {
+		m := muxers[i]
+		if m.path != table.path || len(m.packets) != table.packets || !m.trailer {
+			t.Errorf("Test %d segment is incorrect, got: %s with %d packets, want: %s with %d packets",
+				i, m.path, len(m.packets), table.path, table.packets)
+			continue
+		}
+
+		if m.packets[0].Time != table.first {
+			t.Errorf("Test %d segment starts at %s, want: %s", i, m.packets[0].Time, table.first)
+		}
+	}
+}
+
+func TestRecordConfigValidate(t *testing.T) {
+	tables := []struct {
+		config RecordConfig
+		result bool
+	}{
+		{RecordConfig{Path: "archive.flv"}, true},
+		{RecordConfig{Path: "archive.MP4", SegmentSize: 1 << 20}, true},
+		{RecordConfig{Path: "archive.avi"}, false},
+		{RecordConfig{Path: "archive.flv", SegmentDuration: -time.Second}, false},
+	}
+
+	for i, table := range tables {
+		err := table.config.Validate()
+		if (err == nil) != table.result {
+			t.Errorf("Test %d Validate is incorrect, got err: %v, want success: %t", i, err, table.result)
+		}
+	}
+}
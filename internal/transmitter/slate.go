@@ -0,0 +1,162 @@
+package transmitter
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/nareix/joy4/av"
+)
+
+// slate is a pre-encoded clip looped into destinations while the source is
+// down, so viewers do not see a dead stream.
+type slate struct {
+	// streams is the codec data of the slate in source stream order.
+	streams []av.CodecData
+	packets []av.Packet
+}
+
+// loadSlate reads the slate into memory from its first keyframe on, its
+// streams are mapped onto the source streams by codec type.
+func loadSlate(open func(url string) (av.DemuxCloser, error), path string, streams []av.CodecData) (*slate, error) {
+	conn, err := open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open slate: %s", err.Error())
+	}
+	defer conn.Close()
+
+	slateStreams, err := conn.Streams()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire slate streams: %s", err.Error())
+	}
+
+	mapping, err := mapStreams(streams, slateStreams)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &slate{streams: make([]av.CodecData, len(streams))}
+	for i, stream := range slateStreams {
+		s.streams[mapping[i]] = stream
+	}
+
+	for {
+		pkt, err := conn.ReadPacket()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read slate packet: %s", err.Error())
+		}
+
+		if int(pkt.Idx) >= len(mapping) {
+			continue
+		}
+
+		pkt.Idx = int8(mapping[pkt.Idx])
+		if len(s.packets) == 0 && hasVideo(streams) && !isVideoKeyFrame(streams, pkt) {
+			continue
+		}
+
+		s.packets = append(s.packets, pkt)
+	}
+
+	if len(s.packets) == 0 {
+		return nil, fmt.Errorf("slate has no packets")
+	}
+
+	return s, nil
+}
+
+// mapStreams returns the source stream index of every slate stream, both
+// must have the same codecs.
+func mapStreams(streams, slateStreams []av.CodecData) ([]int, error) {
+	if len(streams) != len(slateStreams) {
+		return nil, fmt.Errorf("slate has %d streams, source has %d", len(slateStreams), len(streams))
+	}
+
+	mapping := make([]int, len(slateStreams))
+	used := make([]bool, len(streams))
+
+	for i, slateStream := range slateStreams {
+		mapping[i] = -1
+
+		for j, stream := range streams {
+			if !used[j] && stream.Type() == slateStream.Type() {
+				mapping[i] = j
+				used[j] = true
+				break
+			}
+		}
+
+		if mapping[i] < 0 {
+			return nil, fmt.Errorf("slate codec %s does not match the source codecs", slateStream.Type())
+		}
+	}
+
+	return mapping, nil
+}
+
+// play loops the slate in real time until stop is closed, timestamps
+// continue on tl.
+func (s *slate) play(tl *timeline, send func(av.Packet), stop <-chan struct{}) {
+	pc := newPacer()
+
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		tl.Rebase()
+
+		for _, pkt := range s.packets {
+			tl.Apply(&pkt)
+
+			if err := pc.Wait(pkt.Time, stop); err != nil {
+				return
+			}
+
+			send(pkt)
+		}
+	}
+}
+
+// startSlate plays the slate into destinations until the returned function
+// is called. Destinations are sent the slate codec data first when it
+// differs from streams, the codec data they use.
+func (t *Transmitter) startSlate(s *slate, streams []av.CodecData, tl *timeline, destinations []*destination) func() {
+	t.logger.Info("playing slate while the source is down")
+
+	if !sameCodecData(streams, s.streams) {
+		for _, d := range destinations {
+			d.SendHeader(s.streams)
+		}
+	}
+
+	t.lock.Lock()
+	t.slateActive = true
+	t.lock.Unlock()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		s.play(tl, func(pkt av.Packet) {
+			for _, d := range destinations {
+				d.Send(pkt)
+			}
+		}, stop)
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+
+		t.lock.Lock()
+		t.slateActive = false
+		t.lock.Unlock()
+	}
+}
@@ -0,0 +1,155 @@
+package transmitter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nareix/joy4/av"
+)
+
+func TestMapStreams(t *testing.T) {
+	streams := []av.CodecData{testCodecData{av.H264}, testCodecData{av.AAC}}
+
+	mapping, err := mapStreams(streams, []av.CodecData{testCodecData{av.AAC}, testCodecData{av.H264}})
+	if err != nil {
+		t.Fatalf("mapStreams failed with err: %s", err)
+	}
+	if mapping[0] != 1 || mapping[1] != 0 {
+		t.Errorf("got mapping %v, want [1 0]", mapping)
+	}
+
+	if _, err := mapStreams(streams, []av.CodecData{testCodecData{av.H264}}); err == nil {
+		t.Errorf("slate without audio was mapped onto a source with audio")
+	}
+
+	if _, err := mapStreams(streams, []av.CodecData{testCodecData{av.H264}, testCodecData{av.H264}}); err == nil {
+		t.Errorf("slate with two video streams was mapped")
+	}
+}
+
+func TestTransmitterSlate(t *testing.T) {
+	streams := []av.CodecData{testCodecData{av.H264}}
+	source := newTestDemuxer(streams...)
+	reconnected := newTestDemuxer(streams...)
+	slateFile := newTestDemuxer(streams...)
+	dst := &testMuxer{}
+
+	sources := []*testDemuxer{source, reconnected}
+	refused := make(chan struct{})
+	tr := newTestTransmitter(source, dst)
+	tr.slate = "slate.flv"
+	tr.reconnect = ReconnectConfig{MaxAttempts: 1, InitialDelay: 100 * time.Millisecond, MaxDelay: 100 * time.Millisecond}
+	tr.open = func(url string) (av.DemuxCloser, error) {
+		if len(sources) == 0 {
+			close(refused)
+			return nil, errors.New("connection refused")
+		}
+		src := sources[0]
+		sources = sources[1:]
+		return src, nil
+	}
+	tr.openFile = func(path string) (av.DemuxCloser, error) { return slateFile, nil }
+
+	// the slate starts on its keyframe
+	slateFile.packets <- av.Packet{Idx: 0, Time: 0, Data: []byte("slate")}
+	for i := 0; i < 3; i++ {
+		slateFile.packets <- av.Packet{Idx: 0, IsKeyFrame: i == 0, Time: time.Duration(i) * 10 * time.Millisecond, Data: []byte("slate")}
+	}
+	close(slateFile.packets)
+
+	source.packets <- av.Packet{Idx: 0, IsKeyFrame: true, Time: 10 * time.Second}
+	close(source.packets)
+
+	// the reconnected source is switched back to on its keyframe
+	reconnected.packets <- av.Packet{Idx: 0, Time: 20 * time.Second}
+	reconnected.packets <- av.Packet{Idx: 0, IsKeyFrame: true, Time: 21 * time.Second}
+	close(reconnected.packets)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := tr.Start(ctx)
+
+	// the slate keeps playing once the source can not be reconnected
+	select {
+	case <-refused:
+	case <-time.After(time.Second):
+		t.Fatalf("source was not reconnected twice")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if !tr.Stats().Slate {
+		t.Errorf("slate is not playing after the reconnect failed")
+	}
+	cancel()
+
+	if err := awaitError(t, errCh); err != nil {
+		t.Fatalf("got err: %s, want graceful stop", err)
+	}
+
+	if len(dst.packets) < 4 {
+		t.Fatalf("got %d packets, want the source, slate and reconnected source packets", len(dst.packets))
+	}
+
+	if string(dst.packets[1].Data) != "slate" || !dst.packets[1].IsKeyFrame {
+		t.Errorf("got packet %+v after the source dropped, want the slate keyframe", dst.packets[1])
+	}
+
+	// the slate plays again once the reconnected source ends
+	sourcePackets := []av.Packet{}
+	for _, pkt := range dst.packets[1:] {
+		if string(pkt.Data) != "slate" {
+			sourcePackets = append(sourcePackets, pkt)
+		}
+	}
+	if len(sourcePackets) != 1 || !sourcePackets[0].IsKeyFrame {
+		t.Errorf("got reconnected source packets %+v, want its keyframe only", sourcePackets)
+	}
+
+	for i := 1; i < len(dst.packets); i++ {
+		if dst.packets[i].Time <= dst.packets[i-1].Time {
+			t.Errorf("packet %d at %s does not follow %s", i, dst.packets[i].Time, dst.packets[i-1].Time)
+		}
+	}
+}
+
+func TestTransmitterSlateWithoutReconnect(t *testing.T) {
+	streams := []av.CodecData{testH264CodecData(t, testSPS720p)}
+	slateStreams := []av.CodecData{testH264CodecData(t, testSPS480p)}
+	source := newTestDemuxer(streams...)
+	slateFile := newTestDemuxer(slateStreams...)
+	dst := &testMuxer{}
+
+	tr := newTestTransmitter(source, dst)
+	tr.slate = "slate.flv"
+	tr.openFile = func(path string) (av.DemuxCloser, error) { return slateFile, nil }
+
+	slateFile.packets <- av.Packet{Idx: 0, IsKeyFrame: true, Data: []byte("slate")}
+	close(slateFile.packets)
+
+	source.packets <- av.Packet{Idx: 0, IsKeyFrame: true}
+	close(source.packets)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := tr.Start(ctx)
+
+	deadline := time.Now().Add(time.Second)
+	for !tr.Stats().Slate {
+		if time.Now().After(deadline) {
+			t.Fatalf("slate did not play with reconnects disabled")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	if err := awaitError(t, errCh); err != nil {
+		t.Fatalf("got err: %s, want graceful stop", err)
+	}
+
+	if len(dst.headers) != 2 || !sameCodecData(dst.headers[1], slateStreams) {
+		t.Fatalf("slate codec data was not sent as a header, got %d headers", len(dst.headers))
+	}
+	if len(dst.packets) < 2 || string(dst.packets[1].Data) != "slate" {
+		t.Errorf("got %d packets, want the source keyframe and the slate", len(dst.packets))
+	}
+}
@@ -0,0 +1,138 @@
+package transmitter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nareix/joy4/av"
+)
+
+// statsWindow is the period current bitrate and fps are measured over.
+const statsWindow = 2 * time.Second
+
+type StreamStats struct {
+	Idx     int    `json:"idx"`
+	Codec   string `json:"codec"`
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+type Stats struct {
+	Streams          []StreamStats      `json:"streams"`
+	Bitrate          float64            `json:"bitrate"`
+	AverageBitrate   float64            `json:"average_bitrate"`
+	FPS              float64            `json:"fps"`
+	KeyFrames        uint64             `json:"keyframes"`
+	LastKeyFrame     time.Time          `json:"last_keyframe"`
+	WriteLatency     time.Duration      `json:"write_latency"`
+	SourceReconnects uint64             `json:"source_reconnects"`
+	ActiveSource     int                `json:"active_source"`
+	Slate            bool               `json:"slate"`
+	Reconnects       uint64             `json:"reconnects"`
+	Dropped          uint64             `json:"dropped"`
+	Destinations     []DestinationStats `json:"destinations"`
+}
+
+// statsCollector counts packets read from the source.
+type statsCollector struct {
+	now func() time.Time
+
+	lock         sync.Mutex
+	streams      []StreamStats
+	video        map[int8]bool
+	started      time.Time
+	totalBytes   uint64
+	keyFrames    uint64
+	lastKeyFrame time.Time
+
+	windowStart  time.Time
+	windowBytes  uint64
+	windowFrames int
+	firstFrame   time.Duration
+	lastFrame    time.Duration
+
+	bitrate float64
+	fps     float64
+}
+
+func newStatsCollector() *statsCollector {
+	return &statsCollector{
+		now:   time.Now,
+		video: map[int8]bool{},
+	}
+}
+
+func (s *statsCollector) Reset(streams []av.CodecData) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	s.started = now
+	s.windowStart = now
+	s.streams = make([]StreamStats, len(streams))
+	for i, stream := range streams {
+		s.streams[i] = StreamStats{Idx: i, Codec: stream.Type().String()}
+		s.video[int8(i)] = stream.Type().IsVideo()
+	}
+}
+
+func (s *statsCollector) Observe(pkt av.Packet) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	size := uint64(len(pkt.Data))
+
+	if int(pkt.Idx) < len(s.streams) {
+		s.streams[pkt.Idx].Packets++
+		s.streams[pkt.Idx].Bytes += size
+	}
+	s.totalBytes += size
+	s.windowBytes += size
+
+	if s.video[pkt.Idx] {
+		if pkt.IsKeyFrame {
+			s.keyFrames++
+			s.lastKeyFrame = now
+		}
+
+		if s.windowFrames == 0 {
+			s.firstFrame = pkt.Time
+		}
+		s.lastFrame = pkt.Time
+		s.windowFrames++
+	}
+
+	elapsed := now.Sub(s.windowStart)
+	if elapsed < statsWindow {
+		return
+	}
+
+	s.bitrate = float64(s.windowBytes*8) / elapsed.Seconds()
+	if span := s.lastFrame - s.firstFrame; s.windowFrames > 1 && span > 0 {
+		s.fps = float64(s.windowFrames-1) / span.Seconds()
+	}
+
+	s.windowStart = now
+	s.windowBytes = 0
+	s.windowFrames = 0
+}
+
+func (s *statsCollector) Snapshot() Stats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stats := Stats{
+		Streams:      append([]StreamStats(nil), s.streams...),
+		Bitrate:      s.bitrate,
+		FPS:          s.fps,
+		KeyFrames:    s.keyFrames,
+		LastKeyFrame: s.lastKeyFrame,
+	}
+
+	if elapsed := s.now().Sub(s.started); !s.started.IsZero() && elapsed > 0 {
+		stats.AverageBitrate = float64(s.totalBytes*8) / elapsed.Seconds()
+	}
+
+	return stats
+}
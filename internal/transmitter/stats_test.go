@@ -0,0 +1,53 @@
+package transmitter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nareix/joy4/av"
+)
+
+func TestStatsCollector(t *testing.T) {
+	now := time.Unix(0, 0)
+
+	s := newStatsCollector()
+	s.now = func() time.Time { return now }
+	s.Reset([]av.CodecData{testCodecData{av.H264}, testCodecData{av.AAC}})
+
+	// 25 fps video with 1000 byte frames and a keyframe every second,
+	// 500 byte audio packets every 40ms
+	for i := 0; i <= 50; i++ {
+		ts := time.Duration(i) * 40 * time.Millisecond
+		now = time.Unix(0, 0).Add(ts)
+
+		s.Observe(av.Packet{Idx: 0, IsKeyFrame: i%25 == 0, Time: ts, Data: make([]byte, 1000)})
+		s.Observe(av.Packet{Idx: 1, Time: ts, Data: make([]byte, 500)})
+	}
+
+	stats := s.Snapshot()
+
+	if stats.Streams[0].Packets != 51 || stats.Streams[0].Bytes != 51000 {
+		t.Errorf("got video stream stats %+v, want 51 packets and 51000 bytes", stats.Streams[0])
+	}
+
+	if stats.Streams[1].Packets != 51 || stats.Streams[1].Bytes != 25500 {
+		t.Errorf("got audio stream stats %+v, want 51 packets and 25500 bytes", stats.Streams[1])
+	}
+
+	if stats.KeyFrames != 3 {
+		t.Errorf("got %d keyframes, want 3", stats.KeyFrames)
+	}
+
+	if stats.FPS < 24.9 || stats.FPS > 25.1 {
+		t.Errorf("got %f fps, want 25", stats.FPS)
+	}
+
+	// 1500 bytes every 40ms
+	if stats.Bitrate < 290000 || stats.Bitrate > 310000 {
+		t.Errorf("got %f bitrate, want 300000", stats.Bitrate)
+	}
+
+	if stats.AverageBitrate < 290000 || stats.AverageBitrate > 310000 {
+		t.Errorf("got %f average bitrate, want 300000", stats.AverageBitrate)
+	}
+}
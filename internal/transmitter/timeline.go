@@ -0,0 +1,36 @@
+package transmitter
+
+import (
+	"time"
+
+	"github.com/nareix/joy4/av"
+)
+
+// rebaseGap separates the last packet before a rebase from the first one
+// after it.
+const rebaseGap = 40 * time.Millisecond
+
+// timeline keeps packet timestamps monotonic for destinations when the
+// source timeline restarts, e.g. after a reconnect.
+type timeline struct {
+	offset time.Duration
+	last   time.Duration
+	rebase bool
+}
+
+// Rebase continues the timeline from the next packet on.
+func (tl *timeline) Rebase() {
+	tl.rebase = true
+}
+
+func (tl *timeline) Apply(pkt *av.Packet) {
+	if tl.rebase {
+		tl.offset = tl.last + rebaseGap - pkt.Time
+		tl.rebase = false
+	}
+
+	pkt.Time += tl.offset
+	if pkt.Time > tl.last {
+		tl.last = pkt.Time
+	}
+}
@@ -0,0 +1,37 @@
+package transmitter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nareix/joy4/av"
+)
+
+func TestTimelineRebase(t *testing.T) {
+	tl := new(timeline)
+
+	tables := []struct {
+		in     time.Duration
+		rebase bool
+		out    time.Duration
+	}{
+		{0, false, 0},
+		{time.Second, false, time.Second},
+		{2 * time.Second, false, 2 * time.Second},
+		{0, true, 2*time.Second + rebaseGap},
+		{time.Second, false, 3*time.Second + rebaseGap},
+		{10 * time.Second, true, 3*time.Second + 2*rebaseGap},
+	}
+
+	for i, table := range tables {
+		if table.rebase {
+			tl.Rebase()
+		}
+
+		pkt := av.Packet{Time: table.in}
+		tl.Apply(&pkt)
+		if pkt.Time != table.out {
+			t.Errorf("Test %d Apply is incorrect, got: %s, want: %s", i, pkt.Time, table.out)
+		}
+	}
+}
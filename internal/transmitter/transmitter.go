@@ -1,13 +1,17 @@
 package transmitter
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"reflect"
+	"sync"
+	"time"
 
+	"github.com/VideoCoin/cli/internal/source"
 	"github.com/nareix/joy4/av"
 	"github.com/nareix/joy4/av/avutil"
 	"github.com/nareix/joy4/format"
-	"github.com/nareix/joy4/format/rtmp"
 
 	"github.com/sirupsen/logrus"
 )
@@ -16,56 +20,505 @@ func init() {
 	format.RegisterAll()
 }
 
+const (
+	// stopTimeout bounds flushing live destination queues on stop, the
+	// ones that are still writing afterwards are closed. Recordings are
+	// drained without a timeout.
+	stopTimeout = 5 * time.Second
+	// recoverInterval is the delay between attempts to switch back to the
+	// primary source while a backup is active.
+	recoverInterval = 5 * time.Second
+)
+
+// MinStallTimeout is the shortest stall timeout, the source is checked
+// four times per timeout.
+const MinStallTimeout = time.Second
+
 type TransmitterConfig struct {
-	Source      string
+	Source string
+	// Backups are switched to in order when the active source fails or
+	// stalls for StallTimeout, Source is switched back to once it
+	// recovers. Their streams must match the streams of Source. Recovery
+	// opens a second connection to Source while the backup is still
+	// read, the backup is closed once Source delivered a keyframe.
+	Backups []string
+	// StallTimeout below MinStallTimeout is raised to it, zero disables
+	// stall detection.
+	StallTimeout time.Duration
+	// Slate is a file looped into destinations while the source is down,
+	// until the transmitter is stopped when the source is not reconnected.
+	// Its codecs must match the source codecs, its codec data is sent as a
+	// new header when it differs.
+	Slate string
+	// File sources are paced by packet timestamps against the wall clock
+	// instead of being read as fast as possible, Loop restarts them at the
+	// end.
+	File bool
+	Loop bool
+	// Ingest replaces Source with the connections of rtmp publishers.
+	Ingest      *Ingest
 	Destination string
-	Logger      *logrus.Entry
+	// Simulcast destinations receive the same packets as Destination,
+	// their failures are logged and do not stop the transmitter. One that
+	// can not be opened is reconnected per Reconnect.
+	Simulcast []string
+	// Record muxes the same packets into local files when set.
+	Record    *RecordConfig
+	Reconnect ReconnectConfig
+	Logger    *logrus.Entry
 }
 
 type Transmitter struct {
-	source      string
-	destination string
-	logger      *logrus.Entry
+	sources      []string
+	stallTimeout time.Duration
+	slate        string
+	file         bool
+	loop         bool
+	destination  string
+	simulcast    []string
+	record       *RecordConfig
+	reconnect    ReconnectConfig
+	logger       *logrus.Entry
+
+	open            func(url string) (av.DemuxCloser, error)
+	openFile        func(path string) (av.DemuxCloser, error)
+	create          func(path string) (av.MuxCloser, error)
+	dial            func(url string) (av.MuxCloser, error)
+	stats           *statsCollector
+	stopTimeout     time.Duration
+	recoverInterval time.Duration
+
+	// halt is closed when the transmitter stops, it interrupts reconnects
+	// and pacing.
+	halt     chan struct{}
+	haltOnce sync.Once
 
-	srcConn av.DemuxCloser
-	dstConn av.DemuxCloser
+	lock             sync.Mutex
+	srcConn          av.DemuxCloser
+	srcStreams       []av.CodecData
+	srcClosed        bool
+	active           int
+	lastRead         time.Time
+	slateActive      bool
+	sourceReconnects uint64
+	destinations     []*destination
 }
 
 func NewTransmitter(c TransmitterConfig) *Transmitter {
-	return &Transmitter{
-		source:      c.Source,
-		destination: c.Destination,
-		logger:      c.Logger.WithField("component", "transmitter"),
+	t := &Transmitter{
+		sources:         append([]string{c.Source}, c.Backups...),
+		stallTimeout:    c.StallTimeout,
+		slate:           c.Slate,
+		file:            c.File,
+		loop:            c.Loop,
+		destination:     c.Destination,
+		simulcast:       c.Simulcast,
+		record:          c.Record,
+		reconnect:       c.Reconnect,
+		logger:          c.Logger.WithField("component", "transmitter"),
+		open:            source.Open,
+		openFile:        avutil.Open,
+		create:          avutil.Create,
+		dial:            dialDestination,
+		stats:           newStatsCollector(),
+		stopTimeout:     stopTimeout,
+		recoverInterval: recoverInterval,
+		halt:            make(chan struct{}),
+	}
+
+	if t.stallTimeout > 0 && t.stallTimeout < MinStallTimeout {
+		t.stallTimeout = MinStallTimeout
+	}
+
+	if c.Ingest != nil {
+		t.open = func(string) (av.DemuxCloser, error) {
+			return c.Ingest.Accept(t.halt)
+		}
 	}
+
+	return t
 }
 
-func (t *Transmitter) Start() error {
-	srcConn, err := avutil.Open(t.source)
+func (t *Transmitter) openSource(idx int) ([]av.CodecData, error) {
+	srcConn, err := t.open(t.sources[idx])
+	if err == errStopped {
+		return nil, err
+	}
 	if err != nil {
-		return fmt.Errorf("failed to open source connection: %s", err.Error())
+		return nil, fmt.Errorf("failed to open source connection: %s", err.Error())
+	}
+
+	streams, err := srcConn.Streams()
+	if err != nil {
+		srcConn.Close()
+		return nil, fmt.Errorf("failed to dial source connection streams: %s", err.Error())
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// the transmitter stopped while the source was opened
+	if t.srcClosed {
+		srcConn.Close()
+		return nil, errStopped
 	}
 	t.srcConn = srcConn
-	defer srcConn.Close()
+	t.srcStreams = streams
+	t.active = idx
+	t.lastRead = time.Now()
 
-	dstConn, err := rtmp.Dial(t.destination)
+	return streams, nil
+}
+
+// rewindSource reopens a looped file source from the beginning.
+func (t *Transmitter) rewindSource(streams []av.CodecData) error {
+	t.closeSource()
+
+	newStreams, err := t.openSource(0)
 	if err != nil {
-		return fmt.Errorf("failed to dial destination connection: %s", err.Error())
+		return err
 	}
-	t.dstConn = dstConn
-	defer dstConn.Close()
 
-	streams, err := srcConn.Streams()
+	if !compatibleStreams(streams, newStreams) {
+		return fmt.Errorf("source streams changed")
+	}
+
+	return nil
+}
+
+func (t *Transmitter) closeSource() {
+	t.lock.Lock()
+	srcConn := t.srcConn
+	t.lock.Unlock()
+
+	if srcConn != nil {
+		srcConn.Close()
+	}
+}
+
+// stopSource closes the source for good, a pending read returns and no
+// connection is opened afterwards.
+func (t *Transmitter) stopSource() {
+	t.lock.Lock()
+	t.srcClosed = true
+	t.lock.Unlock()
+
+	t.closeSource()
+}
+
+func (t *Transmitter) stop() {
+	t.haltOnce.Do(func() {
+		close(t.halt)
+	})
+}
+
+func (t *Transmitter) stopped() bool {
+	select {
+	case <-t.halt:
+		return true
+	default:
+		return false
+	}
+}
+
+func (t *Transmitter) newDestination(url string, primary bool) *destination {
+	d := newDestination(url, primary, t.logger)
+	d.dial = func() (av.MuxCloser, error) {
+		return t.dial(url)
+	}
+
+	return d
+}
+
+// Start transmits the source in the background until ctx is done, the
+// source ends or the primary destination fails. The terminal error, nil
+// when the transmitter stopped gracefully, is sent on the returned channel
+// which is closed once every destination is flushed and closed.
+func (t *Transmitter) Start(ctx context.Context) <-chan error {
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(errCh)
+
+		done := make(chan struct{})
+		defer close(done)
+
+		go func() {
+			select {
+			case <-ctx.Done():
+				t.stop()
+				t.stopSource()
+			case <-done:
+			}
+		}()
+
+		errCh <- t.run()
+	}()
+
+	return errCh
+}
+
+func (t *Transmitter) run() error {
+	defer t.stop()
+
+	streams, err := t.openFirstSource()
+	if err == errStopped {
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to dial source connection streams: %s", err.Error())
+		return err
+	}
+	defer t.stopSource()
+
+	t.stats.Reset(streams)
+
+	var sl *slate
+	if t.slate != "" {
+		var serr error
+		sl, serr = loadSlate(t.openFile, t.slate, streams)
+		if serr != nil {
+			t.logger.WithError(serr).Error("failed to load slate")
+		}
+	}
+
+	primary := t.newDestination(t.destination, true)
+
+	destinations := []*destination{primary}
+	for _, url := range t.simulcast {
+		destinations = append(destinations, t.newDestination(url, false))
+	}
+	if t.record != nil {
+		destinations = append(destinations, newRecordDestination(*t.record, t.create, t.logger))
+	}
+
+	for _, d := range destinations {
+		d.reconnect = t.reconnect
+		d.stop = t.halt
+	}
+
+	if err := primary.Open(streams); err != nil {
+		return err
+	}
+	defer primary.Close()
+
+	running := []*destination{primary}
+	for _, d := range destinations[1:] {
+		if err := d.Open(streams); err != nil {
+			d.logger.WithError(err).Error("failed to open destination")
+			continue
+		}
+		defer d.Close()
+		running = append(running, d)
+	}
+
+	t.lock.Lock()
+	t.destinations = destinations
+	t.lock.Unlock()
+
+	var wg sync.WaitGroup
+	primaryErrCh := make(chan error, 1)
+
+	for _, d := range running {
+		wg.Add(1)
+		go func(d *destination) {
+			defer wg.Done()
+
+			err := d.Run()
+			if err == nil || err == errStopped {
+				return
+			}
+
+			if d.primary {
+				primaryErrCh <- err
+				// unblocks a pending read
+				t.stopSource()
+				return
+			}
+
+			d.logger.WithError(err).Error("destination failed")
+		}(d)
 	}
 
-	if err := dstConn.WriteHeader(streams); err != nil {
-		return fmt.Errorf("failed to write header: %s", err.Error())
+	// finish lets destinations write their queued packets and trailers,
+	// live ones that do not make it in time are closed.
+	finish := func() {
+		for _, d := range running {
+			d.Finish()
+		}
+
+		flushed := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(flushed)
+		}()
+
+		select {
+		case <-flushed:
+		case <-time.After(t.stopTimeout):
+			t.logger.Warn("destinations were not flushed in time")
+			t.stop()
+			// a recording closed before its trailer does not play, it is
+			// left to drain however long its queue takes
+			recording := false
+			for _, d := range running {
+				if d.local {
+					recording = true
+					continue
+				}
+				d.Close()
+			}
+			if recording {
+				t.logger.Info("waiting for the recording to be written")
+			}
+			<-flushed
+		}
 	}
 
+	// primaryErr returns the error of a failed primary destination, the
+	// source is stopped for it and reads and reconnects fail afterwards.
+	primaryErr := func() error {
+		select {
+		case err := <-primaryErrCh:
+			return err
+		default:
+			return nil
+		}
+	}
+
+	if t.stallTimeout > 0 && !t.file {
+		go t.watchStall()
+	}
+
+	tl := new(timeline)
+	pc := newPacer()
+	// packets read since the source was opened, a looped file without
+	// packets would otherwise be rewound forever
+	read := 0
+	// a switched source is joined on its next keyframe
+	waitKeyFrame := false
+	// next is the keyframe a recovered primary source was probed with
+	var next *av.Packet
+	recovered := make(chan *recovery, 1)
+	recovering := false
+
 	for {
+		select {
+		case err := <-primaryErrCh:
+			finish()
+			return err
+		case <-t.halt:
+			finish()
+			return nil
+		case r := <-recovered:
+			recovering = false
+			if t.takeOver(r) {
+				t.logger.Info("switched back to the primary source")
+				streams = t.updateHeader(streams, running)
+				tl.Rebase()
+				read = 0
+				waitKeyFrame = false
+				next = &r.pkt
+			}
+		default:
+		}
+
+		t.lock.Lock()
+		srcConn := t.srcConn
+		t.lock.Unlock()
+
 		var pkt av.Packet
-		if pkt, err = srcConn.ReadPacket(); err != nil {
+		if next != nil {
+			pkt, next, err = *next, nil, nil
+		} else {
+			pkt, err = srcConn.ReadPacket()
+		}
+		if err != nil {
+			if perr := primaryErr(); perr != nil {
+				finish()
+				return perr
+			}
+
+			if t.stopped() {
+				finish()
+				return nil
+			}
+
+			if err == io.EOF && t.file && t.loop && read > 0 {
+				rerr := t.rewindSource(streams)
+				if rerr == nil {
+					t.logger.Debug("source file rewound")
+					streams = t.updateHeader(streams, running)
+					tl.Rebase()
+					read = 0
+					continue
+				}
+				if rerr == errStopped {
+					finish()
+					return nil
+				}
+
+				finish()
+				return fmt.Errorf("failed to rewind source file: %s", rerr.Error())
+			}
+
+			// a source that can only be read once has ended for good
+			once := len(t.sources) == 1 && source.Once(t.sources[0])
+
+			// the slate covers the source being down, also when it is
+			// not reconnected
+			if !t.file && !once && (t.reconnect.MaxAttempts > 0 || len(t.sources) > 1 || sl != nil) {
+				t.logger.WithError(err).Warn("source connection lost")
+
+				var stopSlate func()
+				if sl != nil {
+					stopSlate = t.startSlate(sl, streams, tl, running)
+					streams = sl.streams
+				}
+
+				rerr := t.reconnectSource(streams)
+				if rerr != nil && rerr != errStopped && stopSlate != nil {
+					t.logger.WithError(rerr).Error("failed to reconnect source, playing the slate until stopped")
+
+					select {
+					case perr := <-primaryErrCh:
+						stopSlate()
+						finish()
+						return perr
+					case <-t.halt:
+						rerr = errStopped
+					}
+				}
+				if stopSlate != nil {
+					stopSlate()
+				}
+
+				if rerr == nil {
+					streams = t.updateHeader(streams, running)
+					tl.Rebase()
+					read = 0
+					waitKeyFrame = true
+
+					if t.activeSource() != 0 && !recovering {
+						recovering = true
+						go t.recoverPrimary(streams, recovered)
+					}
+					continue
+				}
+				if rerr == errStopped {
+					finish()
+					return primaryErr()
+				}
+
+				t.logger.WithError(rerr).Error("failed to reconnect source")
+			}
+
+			finish()
+
+			// the failed primary closed the source, its error is the cause
+			if perr := primaryErr(); perr != nil {
+				return perr
+			}
+
 			if err == io.EOF {
 				return nil
 			}
@@ -73,18 +526,108 @@ func (t *Transmitter) Start() error {
 			return fmt.Errorf("read source packet failed with error: %s", err)
 		}
 
-		err := dstConn.WritePacket(pkt)
-		if err != nil {
-			return fmt.Errorf("write destination packet failed with error: %s", err)
+		read++
+		t.lock.Lock()
+		t.lastRead = time.Now()
+		t.lock.Unlock()
+
+		if waitKeyFrame {
+			if hasVideo(streams) && !isVideoKeyFrame(streams, pkt) {
+				continue
+			}
+			waitKeyFrame = false
+		}
+
+		tl.Apply(&pkt)
+
+		if t.file {
+			if err := pc.Wait(pkt.Time, t.halt); err != nil {
+				finish()
+				return nil
+			}
+		}
+
+		t.stats.Observe(pkt)
+
+		for _, d := range running {
+			d.Send(pkt)
 		}
 	}
 }
 
-func (t *Transmitter) Stop() {
-	if t.srcConn != nil {
-		t.srcConn.Close()
+// DestinationStats returns counters of the destination, every simulcast
+// destination and the recording.
+func (t *Transmitter) DestinationStats() []DestinationStats {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	stats := make([]DestinationStats, 0, len(t.destinations))
+	for _, d := range t.destinations {
+		stats = append(stats, d.Stats())
 	}
-	if t.dstConn != nil {
-		t.dstConn.Close()
+
+	return stats
+}
+
+// Stats returns a snapshot of source counters and every destination.
+func (t *Transmitter) Stats() Stats {
+	stats := t.stats.Snapshot()
+	stats.Destinations = t.DestinationStats()
+
+	t.lock.Lock()
+	stats.SourceReconnects = t.sourceReconnects
+	stats.ActiveSource = t.active
+	stats.Slate = t.slateActive
+	t.lock.Unlock()
+
+	stats.Reconnects = stats.SourceReconnects
+	for _, d := range stats.Destinations {
+		stats.Reconnects += d.Reconnects
+		stats.Dropped += d.Dropped
+		if d.Primary {
+			stats.WriteLatency = d.WriteLatency
+		}
+	}
+
+	return stats
+}
+
+// compatibleStreams reports whether a reopened source can continue the
+// stream, its codec data may differ and is sent to destinations again.
+func compatibleStreams(a, b []av.CodecData) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Type() != b[i].Type() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sameCodecData(a, b []av.CodecData) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// updateHeader sends the codec data of the active source to destinations
+// when it differs from streams, e.g. a publisher restarted with new SPS and
+// PPS or a new resolution. It returns the streams destinations now use.
+func (t *Transmitter) updateHeader(streams []av.CodecData, destinations []*destination) []av.CodecData {
+	t.lock.Lock()
+	newStreams := t.srcStreams
+	t.lock.Unlock()
+
+	if sameCodecData(streams, newStreams) {
+		return streams
+	}
+
+	t.logger.Info("source codec data changed, sending a new header")
+	for _, d := range destinations {
+		d.SendHeader(newStreams)
 	}
+
+	return newStreams
 }
@@ -0,0 +1,284 @@
+package transmitter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nareix/joy4/av"
+	"github.com/sirupsen/logrus"
+)
+
+type testDemuxer struct {
+	streams   []av.CodecData
+	packets   chan av.Packet
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newTestDemuxer(streams ...av.CodecData) *testDemuxer {
+	return &testDemuxer{
+		streams: streams,
+		packets: make(chan av.Packet, 16),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (d *testDemuxer) Streams() ([]av.CodecData, error) { return d.streams, nil }
+
+func (d *testDemuxer) ReadPacket() (av.Packet, error) {
+	select {
+	case pkt, ok := <-d.packets:
+		if !ok {
+			return av.Packet{}, io.EOF
+		}
+		return pkt, nil
+	case <-d.closed:
+		return av.Packet{}, io.ErrClosedPipe
+	}
+}
+
+func (d *testDemuxer) Close() error {
+	d.closeOnce.Do(func() { close(d.closed) })
+	return nil
+}
+
+func newTestTransmitter(src *testDemuxer, dst av.MuxCloser) *Transmitter {
+	t := NewTransmitter(TransmitterConfig{
+		Source:      "rtmp://127.0.0.1/source",
+		Destination: "rtmp://127.0.0.1/destination",
+		Logger:      logrus.NewEntry(logrus.New()),
+	})
+	t.open = func(url string) (av.DemuxCloser, error) { return src, nil }
+	t.dial = func(url string) (av.MuxCloser, error) { return dst, nil }
+
+	return t
+}
+
+func awaitError(t *testing.T, errCh <-chan error) error {
+	select {
+	case err := <-errCh:
+		if _, ok := <-errCh; ok {
+			t.Fatalf("error channel is not closed after the terminal error")
+		}
+		return err
+	case <-time.After(time.Second):
+		t.Fatalf("transmitter did not stop")
+		return nil
+	}
+}
+
+func TestTransmitterGracefulStop(t *testing.T) {
+	src := newTestDemuxer(testCodecData{av.H264})
+	dst := &testMuxer{}
+	tr := newTestTransmitter(src, dst)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := tr.Start(ctx)
+
+	for i := 0; i < 3; i++ {
+		src.packets <- av.Packet{Idx: 0, IsKeyFrame: i == 0, Time: time.Duration(i) * 40 * time.Millisecond}
+	}
+
+	for tr.Stats().Streams == nil || tr.Stats().Streams[0].Packets < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	if err := awaitError(t, errCh); err != nil {
+		t.Fatalf("got err: %s, want graceful stop", err)
+	}
+
+	if len(dst.packets) != 3 || !dst.trailer {
+		t.Errorf("got %d packets and trailer %t, want 3 packets flushed and trailer", len(dst.packets), dst.trailer)
+	}
+
+	select {
+	case <-src.closed:
+	default:
+		t.Errorf("source is not closed")
+	}
+}
+
+func TestTransmitterSourceEnded(t *testing.T) {
+	src := newTestDemuxer(testCodecData{av.H264})
+	dst := &testMuxer{}
+	tr := newTestTransmitter(src, dst)
+
+	src.packets <- av.Packet{Idx: 0, IsKeyFrame: true}
+	close(src.packets)
+
+	if err := awaitError(t, tr.Start(context.Background())); err != nil {
+		t.Fatalf("got err: %s, want nil when the source ended", err)
+	}
+
+	if len(dst.packets) != 1 || !dst.trailer {
+		t.Errorf("got %d packets and trailer %t, want 1 packet and trailer", len(dst.packets), dst.trailer)
+	}
+}
+
+func TestTransmitterPrimaryFailed(t *testing.T) {
+	src := newTestDemuxer(testCodecData{av.H264})
+	dst := &failingMuxer{failAt: 1}
+	tr := newTestTransmitter(src, dst)
+
+	errCh := tr.Start(context.Background())
+
+	for i := 0; i < 3; i++ {
+		src.packets <- av.Packet{Idx: 0, IsKeyFrame: i == 0}
+	}
+
+	if err := awaitError(t, errCh); err == nil {
+		t.Fatalf("got no error, want the primary destination error")
+	}
+}
+
+func TestTransmitterResendsHeader(t *testing.T) {
+	streams := []av.CodecData{testH264CodecData(t, testSPS720p)}
+	restarted := []av.CodecData{testH264CodecData(t, testSPS480p)}
+
+	first := newTestDemuxer(streams...)
+	second := newTestDemuxer(restarted...)
+	dst := &testMuxer{}
+
+	tr := newTestTransmitter(first, dst)
+	tr.reconnect = ReconnectConfig{MaxAttempts: 1, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	sources := []*testDemuxer{first, second}
+	tr.open = func(url string) (av.DemuxCloser, error) {
+		if len(sources) == 0 {
+			return nil, errors.New("connection refused")
+		}
+		src := sources[0]
+		sources = sources[1:]
+		return src, nil
+	}
+
+	first.packets <- av.Packet{Idx: 0, IsKeyFrame: true, Time: 0}
+	close(first.packets)
+
+	// the publisher restarted with a new resolution
+	second.packets <- av.Packet{Idx: 0, IsKeyFrame: true, Time: 0}
+	second.packets <- av.Packet{Idx: 0, Time: 40 * time.Millisecond}
+	close(second.packets)
+
+	errCh := tr.Start(context.Background())
+	if err := awaitError(t, errCh); err != nil {
+		t.Fatalf("got err: %s, want source ended", err)
+	}
+
+	if len(dst.headers) != 2 || !sameCodecData(dst.headers[1], restarted) {
+		t.Fatalf("header was not sent again with the new codec data, got %d headers", len(dst.headers))
+	}
+	if len(dst.packets) != 3 {
+		t.Errorf("got %d packets, want 3", len(dst.packets))
+	}
+}
+
+// blockingMuxer blocks writes until it is closed.
+type blockingMuxer struct {
+	testMuxer
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (m *blockingMuxer) WritePacket(pkt av.Packet) error {
+	<-m.closed
+	return io.ErrClosedPipe
+}
+
+func (m *blockingMuxer) Close() error {
+	m.closeOnce.Do(func() { close(m.closed) })
+	return nil
+}
+
+// slowMuxer takes a while for every write.
+type slowMuxer struct {
+	testMuxer
+}
+
+func (m *slowMuxer) WritePacket(pkt av.Packet) error {
+	time.Sleep(10 * time.Millisecond)
+	return m.testMuxer.WritePacket(pkt)
+}
+
+func TestTransmitterDrainsRecording(t *testing.T) {
+	src := newTestDemuxer(testCodecData{av.H264})
+	dst := &blockingMuxer{closed: make(chan struct{})}
+	rec := &slowMuxer{}
+	tr := newTestTransmitter(src, dst)
+	tr.record = &RecordConfig{Path: "stream.flv"}
+	tr.create = func(path string) (av.MuxCloser, error) { return rec, nil }
+	tr.stopTimeout = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := tr.Start(ctx)
+
+	for i := 0; i < 10; i++ {
+		src.packets <- av.Packet{Idx: 0, IsKeyFrame: i == 0, Time: time.Duration(i) * 40 * time.Millisecond}
+	}
+
+	for tr.Stats().Streams == nil || tr.Stats().Streams[0].Packets < 10 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	awaitError(t, errCh)
+
+	if len(rec.packets) != 10 || !rec.trailer {
+		t.Errorf("got %d recorded packets and trailer %t, want 10 packets and trailer", len(rec.packets), rec.trailer)
+	}
+}
+
+func TestTransmitterReopensSimulcast(t *testing.T) {
+	src := newTestDemuxer(testCodecData{av.H264})
+	dst := &testMuxer{}
+	simulcast := &testMuxer{}
+	tr := newTestTransmitter(src, dst)
+	tr.simulcast = []string{"rtmp://127.0.0.1/simulcast/key"}
+	tr.reconnect = ReconnectConfig{MaxAttempts: 3, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	opens := 0
+	tr.open = func(url string) (av.DemuxCloser, error) {
+		opens++
+		if opens > 1 {
+			return nil, errors.New("source ended")
+		}
+		return src, nil
+	}
+
+	var lock sync.Mutex
+	dials := 0
+	tr.dial = func(url string) (av.MuxCloser, error) {
+		if url != tr.simulcast[0] {
+			return dst, nil
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		dials++
+		if dials == 1 {
+			return nil, errors.New("connection refused")
+		}
+		return simulcast, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		src.packets <- av.Packet{Idx: 0, IsKeyFrame: i == 0}
+	}
+	close(src.packets)
+
+	awaitError(t, tr.Start(context.Background()))
+
+	if len(simulcast.packets) != 3 || !simulcast.trailer {
+		t.Errorf("got %d simulcast packets and trailer %t, want 3 packets and trailer", len(simulcast.packets), simulcast.trailer)
+	}
+
+	for _, stats := range tr.DestinationStats() {
+		if stats.URL == tr.simulcast[0] {
+			t.Errorf("got stats of %s, want the stream key redacted", stats.URL)
+		}
+	}
+}